@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/openfaas/certifier/internal/fixtures"
+)
+
+func fixturesBuild(args []string) error {
+	opts := fixtures.Options{}
+
+	fs := flag.NewFlagSet("fixtures build", flag.ContinueOnError)
+	fs.StringVar(&opts.StackFile, "f", "functions/stack.yml", "path to the fixtures stack file")
+	fs.StringVar(&opts.OutputDir, "o", "build/fixtures", "output folder for the image tarballs")
+	fs.StringVar(&opts.Arch, "arch", "amd64", "target architecture of the images")
+	fs.StringVar(&opts.Registry, "registryPrefix", "docker.io", "registry prefix of the image names, must match the tests -registryPrefix")
+	fs.StringVar(&opts.Filter, "filter", "", "only build functions matching this wildcard")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	results, err := fixtures.Build(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		fmt.Printf("%s/%s\t%s\t%s\n", opts.Registry, res.Image, res.Path, res.Digest)
+	}
+	return nil
+}
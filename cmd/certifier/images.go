@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/openfaas/certifier/internal/fixtures"
+	"github.com/openfaas/certifier/internal/images"
+	"github.com/openfaas/certifier/internal/oci"
+	"github.com/openfaas/certifier/internal/registry"
+)
+
+// imageFlags are shared by all `images` sub-commands, they mirror the test
+// flags so that the same resolution applies to the run and the mirror
+type imageFlags struct {
+	registryPrefix string
+	imageConfig    string
+	stackFile      string
+}
+
+func (f *imageFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.registryPrefix, "registryPrefix", "docker.io", "registry prefix, must match the tests -registryPrefix")
+	fs.StringVar(&f.imageConfig, "images", "", "JSON file with image overrides and pinned digests, must match the tests -images")
+	fs.StringVar(&f.stackFile, "f", "functions/stack.yml", "path to the fixtures stack file")
+}
+
+// required returns the resolver and the image names a run needs, with the
+// fixture images reported separately.
+func (f *imageFlags) required() (*images.Resolver, []string, map[string]bool, error) {
+	resolver, err := images.LoadResolver(f.registryPrefix, f.imageConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	list, err := fixtures.Load(f.stackFile, "")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	fixtureImages := []string{}
+	isFixture := map[string]bool{}
+	for _, fixture := range list {
+		fixtureImages = append(fixtureImages, fixture.Image)
+		isFixture[fixture.Image] = true
+	}
+
+	return resolver, images.Required(fixtureImages), isFixture, nil
+}
+
+func imagesList(args []string) error {
+	flags := imageFlags{}
+	fs := flag.NewFlagSet("images list", flag.ContinueOnError)
+	flags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	resolver, required, _, err := flags.required()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range required {
+		fmt.Println(resolver.Resolve(name))
+	}
+	return nil
+}
+
+// imagesSave pulls every image that is not a fixture into a single OCI
+// layout tarball. The fixtures are not published, their tarballs come from
+// `certifier fixtures build`.
+func imagesSave(args []string) error {
+	flags := imageFlags{}
+	output := ""
+	platform := ""
+	client := &registry.Client{}
+
+	fs := flag.NewFlagSet("images save", flag.ContinueOnError)
+	flags.register(fs)
+	fs.StringVar(&output, "o", "build/images.tar", "output OCI image layout tarball")
+	fs.StringVar(&platform, "platform", "linux/amd64", "platform to save from multi-arch images")
+	registerClientFlags(fs, client)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	parts := strings.SplitN(platform, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid platform %q, want os/arch", platform)
+	}
+	target := oci.Platform{OS: parts[0], Architecture: parts[1]}
+
+	resolver, required, isFixture, err := flags.required()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	layout := &oci.Layout{
+		Index: oci.Index{SchemaVersion: 2, MediaType: oci.MediaTypeIndex},
+		Blobs: map[string][]byte{},
+	}
+
+	for _, name := range required {
+		if isFixture[name] {
+			continue
+		}
+
+		source := resolver.Resolve(name)
+		ref, err := images.ParseReference(source)
+		if err != nil {
+			return err
+		}
+
+		desc, err := client.Pull(ctx, ref, target, layout)
+		if err != nil {
+			return fmt.Errorf("unable to pull %s: %w", source, err)
+		}
+
+		desc.Annotations = map[string]string{
+			oci.AnnotationImageName:      source,
+			oci.AnnotationRefName:        ref.Tag,
+			oci.AnnotationCertifierImage: name,
+		}
+		layout.Index.Manifests = append(layout.Index.Manifests, desc)
+		fmt.Printf("%s\t%s\n", source, desc.Digest)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := layout.Write(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// imagesPush pushes the images from one or more OCI layout tarballs to the
+// references the tests will use, e.g. a local mirror set with -registryPrefix.
+func imagesPush(args []string) error {
+	flags := imageFlags{}
+	configOut := ""
+	client := &registry.Client{}
+
+	fs := flag.NewFlagSet("images push", flag.ContinueOnError)
+	flags.register(fs)
+	fs.StringVar(&configOut, "write-images", "", "write an -images JSON file pinning the pushed digests")
+	registerClientFlags(fs, client)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: certifier images push [flags] <image tarball>...")
+	}
+
+	resolver, required, _, err := flags.required()
+	if err != nil {
+		return err
+	}
+
+	// the digests change when the image is pushed, so they are not applied
+	// to the push target but recorded for the next run instead
+	pinned := &images.Resolver{Overrides: resolver.Overrides, Digests: map[string]string{}}
+	target := &images.Resolver{Prefix: resolver.Prefix, Overrides: resolver.Overrides}
+
+	ctx := context.Background()
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		layout, err := oci.ReadLayout(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", path, err)
+		}
+
+		for _, desc := range layout.Index.Manifests {
+			name := desc.Annotations[oci.AnnotationCertifierImage]
+			if name == "" {
+				return fmt.Errorf("%s: manifest %s was not created by the certifier", path, desc.Digest)
+			}
+
+			ref, err := images.ParseReference(target.Resolve(name))
+			if err != nil {
+				return err
+			}
+
+			digest, err := client.Push(ctx, ref, layout, desc)
+			if err != nil {
+				return fmt.Errorf("unable to push %s: %w", ref, err)
+			}
+
+			pinned.Digests[name] = digest
+			fmt.Printf("%s\t%s\n", ref, digest)
+		}
+	}
+
+	for _, name := range required {
+		if _, ok := pinned.Digests[name]; !ok {
+			fmt.Fprintf(os.Stderr, "Warning: %s was not pushed, the run will fail to pull it\n", name)
+		}
+	}
+
+	if configOut != "" {
+		data, err := json.MarshalIndent(pinned, "", "  ")
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(configOut, append(data, '\n'), 0644)
+	}
+	return nil
+}
+
+func registerClientFlags(fs *flag.FlagSet, client *registry.Client) {
+	fs.StringVar(&client.Username, "username", os.Getenv("REGISTRY_USERNAME"), "registry username, defaults to $REGISTRY_USERNAME")
+	fs.StringVar(&client.Password, "password", os.Getenv("REGISTRY_PASSWORD"), "registry password, defaults to $REGISTRY_PASSWORD")
+	fs.BoolVar(&client.PlainHTTP, "plain-http", false, "use http instead of https, localhost always uses http")
+}
@@ -0,0 +1,63 @@
+// Command certifier provides the tooling that supports a certifier run, such
+// as building the fixture function images and mirroring the images a run
+// needs into an air-gapped registry.
+//
+// The certification checks themselves are run with `go test ./tests`.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command is a single `certifier <group> <name>` sub-command
+type command func(args []string) error
+
+var commands = map[string]map[string]command{
+	"fixtures": {
+		"build": fixturesBuild,
+	},
+	"images": {
+		"list": imagesList,
+		"save": imagesSave,
+		"push": imagesPush,
+	},
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: certifier <command> <sub-command> [flags]\n\n%s", usage())
+	}
+
+	group, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage())
+	}
+
+	cmd, ok := group[args[1]]
+	if !ok {
+		return fmt.Errorf("unknown sub-command %q for %s\n\n%s", args[1], args[0], usage())
+	}
+
+	return cmd(args[2:])
+}
+
+func usage() string {
+	lines := []string{"Available commands:"}
+	for group, subs := range commands {
+		for name := range subs {
+			lines = append(lines, fmt.Sprintf("  %s %s", group, name))
+		}
+	}
+	sort.Strings(lines[1:])
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,706 @@
+// Package certifier exposes the provider API checks of the certifier as a
+// library, so that a provider can run them against its FaaSHandlers from an
+// ordinary go test, without a cluster or the gateway:
+//
+//	func Test_Conformance(t *testing.T) {
+//		certifier.RunConformance(t, handlers, certifier.ConformanceOptions{
+//			Namespace: "openfaas-fn",
+//		})
+//	}
+//
+// Only the bookkeeping of the API is checked. Invocation, available replicas
+// and everything else that needs the functions to run is left to the suite in
+// the tests folder.
+package certifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/certifier/internal/images"
+	"github.com/openfaas/certifier/internal/version"
+	"github.com/openfaas/faas-provider/logs"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// NameExpression is the pattern of function names in the faas-provider routes
+const NameExpression = "-a-zA-Z_0-9."
+
+// updatedImage replaces images.Alpine in the update check, the images are
+// never pulled
+const updatedImage = "functions/alpine:3.15"
+
+// ConformanceOptions configures RunConformance, the zero value checks a
+// provider without namespace support.
+type ConformanceOptions struct {
+	// Namespace the functions and secrets are created in, empty leaves the
+	// choice to the provider
+	Namespace string
+	// Namespaces are additional namespaces that /system/namespaces must list,
+	// a function is deployed, described and deleted in each of them
+	Namespaces []string
+	// ImmutableSecrets skips the secret update, e.g. for Docker Swarm
+	ImmutableSecrets bool
+	// DisableScaling skips the scale bookkeeping
+	DisableScaling bool
+	// Timeout is the time allowed for a change to show in describe and list,
+	// defaults to 10s
+	Timeout time.Duration
+}
+
+// RunConformance mounts the handlers on an httptest.Server, using the routes
+// of the faas-provider, and runs the checks of the provider API that do not
+// need a running function. Checks of nil handlers are skipped.
+func RunConformance(t *testing.T, handlers types.FaaSHandlers, opts ConformanceOptions) {
+	t.Helper()
+
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	server := httptest.NewServer(newRouter(handlers))
+	defer server.Close()
+
+	c := &conformance{
+		handlers: handlers,
+		opts:     opts,
+		baseURL:  server.URL,
+		client:   server.Client(),
+	}
+
+	t.Run("info", c.info)
+	t.Run("function lifecycle", func(t *testing.T) {
+		c.functionLifecycle(t, opts.Namespace)
+	})
+	t.Run("scale", c.scale)
+	t.Run("logs", c.logs)
+	t.Run("secrets", c.secrets)
+	t.Run("namespaces", c.namespaces)
+}
+
+// newRouter registers the handlers like the faas-provider Serve, the
+// handlers read the function name from the mux vars.
+func newRouter(handlers types.FaaSHandlers) *mux.Router {
+	r := mux.NewRouter()
+
+	routes := []struct {
+		path    string
+		handler http.HandlerFunc
+		methods []string
+	}{
+		{path: "/system/functions", handler: handlers.FunctionReader, methods: []string{http.MethodGet}},
+		{path: "/system/functions", handler: handlers.DeployHandler, methods: []string{http.MethodPost}},
+		{path: "/system/functions", handler: handlers.DeleteHandler, methods: []string{http.MethodDelete}},
+		{path: "/system/functions", handler: handlers.UpdateHandler, methods: []string{http.MethodPut}},
+		{path: "/system/function/{name:[" + NameExpression + "]+}", handler: handlers.ReplicaReader, methods: []string{http.MethodGet}},
+		{path: "/system/scale-function/{name:[" + NameExpression + "]+}", handler: handlers.ReplicaUpdater, methods: []string{http.MethodPost}},
+		{path: "/system/info", handler: handlers.InfoHandler, methods: []string{http.MethodGet}},
+		{path: "/system/secrets", handler: handlers.SecretHandler,
+			methods: []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete}},
+		{path: "/system/logs", handler: handlers.LogHandler, methods: []string{http.MethodGet}},
+		{path: "/system/namespaces", handler: handlers.ListNamespaceHandler, methods: []string{http.MethodGet}},
+		{path: "/function/{name:[" + NameExpression + "]+}", handler: handlers.FunctionProxy},
+		{path: "/function/{name:[" + NameExpression + "]+}/", handler: handlers.FunctionProxy},
+		{path: "/function/{name:[" + NameExpression + "]+}/{params:.*}", handler: handlers.FunctionProxy},
+		{path: "/healthz", handler: handlers.HealthHandler, methods: []string{http.MethodGet}},
+	}
+
+	for _, route := range routes {
+		if route.handler == nil {
+			continue
+		}
+		rt := r.HandleFunc(route.path, route.handler)
+		if len(route.methods) > 0 {
+			rt.Methods(route.methods...)
+		}
+	}
+
+	return r
+}
+
+type conformance struct {
+	handlers types.FaaSHandlers
+	opts     ConformanceOptions
+	baseURL  string
+	client   *http.Client
+}
+
+// require skips the check when the provider does not implement the handler
+func require(t *testing.T, handler http.HandlerFunc, name string) {
+	t.Helper()
+
+	if handler == nil {
+		t.Skipf("%s is nil", name)
+	}
+}
+
+func (c *conformance) info(t *testing.T) {
+	require(t, c.handlers.InfoHandler, "InfoHandler")
+
+	var info types.ProviderInfo
+	status, body := c.do(t, http.MethodGet, "/system/info", nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("info got %d, wanted %d: %s", status, http.StatusOK, body)
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		t.Fatalf("invalid provider info %q: %s", body, err)
+	}
+
+	if info.Name == "" {
+		t.Error("provider name may not be empty")
+	}
+	if info.Orchestration == "" {
+		t.Error("provider orchestration name may not be empty")
+	}
+	if info.Version == nil {
+		t.Fatal("provider version cannot be empty")
+	}
+
+	release, err := version.Parse(info.Version.Release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// dev builds may not know their commit
+	if !(release.Dev && info.Version.SHA == version.Dev) && !version.ValidSHA(info.Version.SHA) {
+		t.Fatalf("sha %q is not a git commit SHA", info.Version.SHA)
+	}
+}
+
+// functionLifecycle deploys, describes, lists, updates and deletes a
+// function in namespace.
+func (c *conformance) functionLifecycle(t *testing.T, namespace string) {
+	requireFunctionHandlers(t, c.handlers)
+
+	deployment := c.deployment("conformance-lifecycle", namespace)
+	c.deploy(t, http.MethodPost, deployment)
+	defer c.cleanup(deployment)
+
+	c.waitForDescribe(t, deployment)
+
+	t.Run("list", func(t *testing.T) {
+		c.eventually(t, func() error {
+			for _, fn := range c.list(t, namespace) {
+				if fn.Name == deployment.Service {
+					return nil
+				}
+			}
+			return fmt.Errorf("%s is not listed", deployment.Service)
+		})
+	})
+
+	t.Run("describe unknown function", func(t *testing.T) {
+		status, body := c.do(t, http.MethodGet, "/system/function/conformance-does-not-exist", namespaceQuery(namespace), nil)
+		if status != http.StatusNotFound {
+			t.Fatalf("describe got %d, wanted %d: %s", status, http.StatusNotFound, body)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		update := deployment
+		update.Image = updatedImage
+		update.EnvProcess = "cat"
+		update.EnvVars = map[string]string{"revision": "2"}
+		update.Labels = &map[string]string{"conformance": "updated"}
+		update.Annotations = &map[string]string{}
+
+		c.deploy(t, http.MethodPut, update)
+		c.waitForDescribe(t, update)
+	})
+
+	t.Run("update unknown function", func(t *testing.T) {
+		unknown := c.deployment("conformance-does-not-exist", namespace)
+		status, body := c.do(t, http.MethodPut, "/system/functions", nil, unknown)
+		if status != http.StatusNotFound {
+			t.Fatalf("update got %d, wanted %d: %s", status, http.StatusNotFound, body)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		status, body := c.delete(t, deployment.Service, namespace)
+		if status != http.StatusOK && status != http.StatusAccepted {
+			t.Fatalf("delete got %d, wanted %d or %d: %s", status, http.StatusOK, http.StatusAccepted, body)
+		}
+
+		c.eventually(t, func() error {
+			status, _ := c.do(t, http.MethodGet, path.Join("/system/function", deployment.Service), namespaceQuery(namespace), nil)
+			if status != http.StatusNotFound {
+				return fmt.Errorf("describe got %d, wanted %d", status, http.StatusNotFound)
+			}
+			for _, fn := range c.list(t, namespace) {
+				if fn.Name == deployment.Service {
+					return fmt.Errorf("%s is still listed", deployment.Service)
+				}
+			}
+			return nil
+		})
+
+		status, body = c.delete(t, deployment.Service, namespace)
+		if status != http.StatusNotFound {
+			t.Fatalf("second delete got %d, wanted %d: %s", status, http.StatusNotFound, body)
+		}
+	})
+}
+
+func (c *conformance) scale(t *testing.T) {
+	if c.opts.DisableScaling {
+		t.Skip("scaling disabled")
+	}
+	requireFunctionHandlers(t, c.handlers)
+	require(t, c.handlers.ReplicaUpdater, "ReplicaUpdater")
+
+	namespace := c.opts.Namespace
+	deployment := c.deployment("conformance-scale", namespace)
+	c.deploy(t, http.MethodPost, deployment)
+	defer c.cleanup(deployment)
+
+	c.waitForDescribe(t, deployment)
+
+	// only the desired replicas are checked, nothing runs the function
+	for _, replicas := range []uint64{3, 0, 1} {
+		req := types.ScaleServiceRequest{ServiceName: deployment.Service, Replicas: replicas}
+		status, body := c.do(t, http.MethodPost, path.Join("/system/scale-function", deployment.Service), namespaceQuery(namespace), req)
+		if status != http.StatusOK && status != http.StatusAccepted {
+			t.Fatalf("scale to %d got %d, wanted %d or %d: %s", replicas, status, http.StatusOK, http.StatusAccepted, body)
+		}
+
+		c.eventually(t, func() error {
+			fn, status := c.describe(t, deployment.Service, namespace)
+			if status != http.StatusOK {
+				return fmt.Errorf("describe got %d, wanted %d", status, http.StatusOK)
+			}
+			if fn.Replicas != replicas {
+				return fmt.Errorf("got %d replicas, wanted %d", fn.Replicas, replicas)
+			}
+			return nil
+		})
+	}
+
+	req := types.ScaleServiceRequest{ServiceName: "conformance-does-not-exist", Replicas: 1}
+	status, body := c.do(t, http.MethodPost, "/system/scale-function/conformance-does-not-exist", namespaceQuery(namespace), req)
+	if status != http.StatusNotFound {
+		t.Fatalf("scale of an unknown function got %d, wanted %d: %s", status, http.StatusNotFound, body)
+	}
+}
+
+// logs checks the log handler and Requester, the messages must belong to
+// the requested function but a provider without running functions may not
+// have any.
+func (c *conformance) logs(t *testing.T) {
+	requireFunctionHandlers(t, c.handlers)
+	require(t, c.handlers.LogHandler, "LogHandler")
+
+	namespace := c.opts.Namespace
+	deployment := c.deployment("conformance-logs", namespace)
+	c.deploy(t, http.MethodPost, deployment)
+	defer c.cleanup(deployment)
+
+	c.waitForDescribe(t, deployment)
+
+	query := url.Values{}
+	query.Set("name", deployment.Service)
+	query.Set("follow", "false")
+	query.Set("tail", "10")
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.Timeout)
+	defer cancel()
+
+	res := c.request(t, ctx, http.MethodGet, "/system/logs", query, nil)
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(res.Body)
+		t.Fatalf("logs got %d, wanted %d: %s", res.StatusCode, http.StatusOK, body)
+	}
+
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var msg logs.Message
+		err := decoder.Decode(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid log message: %s", err)
+		}
+		if msg.Name != deployment.Service {
+			t.Errorf("got a log message of %q, wanted only %q: %s", msg.Name, deployment.Service, msg)
+		}
+	}
+}
+
+func (c *conformance) secrets(t *testing.T) {
+	require(t, c.handlers.SecretHandler, "SecretHandler")
+
+	namespace := c.opts.Namespace
+	secret := types.Secret{Name: "conformance-secret", Namespace: namespace, Value: "conformance-secret-value"}
+	defer c.quietly(http.MethodDelete, "/system/secrets", nil, types.Secret{Name: secret.Name, Namespace: namespace})
+
+	if c.listSecret(t, namespace, secret.Name) {
+		t.Fatalf("%s already exists", secret.Name)
+	}
+
+	t.Run("create", func(t *testing.T) {
+		status, body := c.do(t, http.MethodPost, "/system/secrets", nil, secret)
+		switch status {
+		case http.StatusCreated, http.StatusAccepted, http.StatusOK:
+			// happy path
+		default:
+			t.Fatalf("create got %d, wanted %d, %d or %d: %s",
+				status, http.StatusOK, http.StatusCreated, http.StatusAccepted, body)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		if !c.listSecret(t, namespace, secret.Name) {
+			t.Fatalf("%s is not listed", secret.Name)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		if c.opts.ImmutableSecrets {
+			t.Skip("secrets are immutable")
+		}
+
+		update := secret
+		update.Value = ""
+		update.RawValue = []byte("conformance-NEW-secret-value")
+		status, body := c.do(t, http.MethodPut, "/system/secrets", nil, update)
+		if status != http.StatusOK && status != http.StatusAccepted {
+			t.Fatalf("update got %d, wanted %d or %d: %s", status, http.StatusOK, http.StatusAccepted, body)
+		}
+	})
+
+	t.Run("update unknown secret", func(t *testing.T) {
+		if c.opts.ImmutableSecrets {
+			t.Skip("secrets are immutable")
+		}
+
+		unknown := types.Secret{Name: "conformance-does-not-exist", Namespace: namespace, Value: "value"}
+		status, body := c.do(t, http.MethodPut, "/system/secrets", nil, unknown)
+		if status != http.StatusNotFound {
+			t.Fatalf("update got %d, wanted %d: %s", status, http.StatusNotFound, body)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		status, body := c.do(t, http.MethodDelete, "/system/secrets", nil, types.Secret{Name: secret.Name, Namespace: namespace})
+		if status != http.StatusOK && status != http.StatusAccepted {
+			t.Fatalf("delete got %d, wanted %d or %d: %s", status, http.StatusOK, http.StatusAccepted, body)
+		}
+
+		c.eventually(t, func() error {
+			if c.listSecret(t, namespace, secret.Name) {
+				return fmt.Errorf("%s is still listed", secret.Name)
+			}
+			return nil
+		})
+	})
+}
+
+func (c *conformance) namespaces(t *testing.T) {
+	require(t, c.handlers.ListNamespaceHandler, "ListNamespaceHandler")
+
+	status, body := c.do(t, http.MethodGet, "/system/namespaces", nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("namespaces got %d, wanted %d: %s", status, http.StatusOK, body)
+	}
+
+	var namespaces []string
+	if err := json.Unmarshal(body, &namespaces); err != nil {
+		t.Fatalf("invalid namespace list %q: %s", body, err)
+	}
+	sort.Strings(namespaces)
+
+	wanted := append([]string{}, c.opts.Namespaces...)
+	if c.opts.Namespace != "" {
+		wanted = append(wanted, c.opts.Namespace)
+	}
+	for _, namespace := range wanted {
+		i := sort.SearchStrings(namespaces, namespace)
+		if i == len(namespaces) || namespaces[i] != namespace {
+			t.Errorf("got %v, wanted %s in the list", namespaces, namespace)
+		}
+	}
+
+	for _, namespace := range c.opts.Namespaces {
+		namespace := namespace
+		t.Run("function lifecycle in "+namespace, func(t *testing.T) {
+			c.functionLifecycle(t, namespace)
+		})
+	}
+
+	t.Run("deploy to unknown namespace", func(t *testing.T) {
+		if c.opts.Namespace == "" && len(c.opts.Namespaces) == 0 {
+			t.Skip("no namespaces configured, the provider may ignore the namespace")
+		}
+		requireFunctionHandlers(t, c.handlers)
+
+		deployment := c.deployment("conformance-namespace", "conformance-does-not-exist")
+		status, body := c.do(t, http.MethodPost, "/system/functions", nil, deployment)
+		if status == http.StatusOK || status == http.StatusAccepted {
+			c.cleanup(deployment)
+		}
+		if status < http.StatusBadRequest || status >= http.StatusInternalServerError {
+			t.Fatalf("deploy got %d, wanted a 4xx status: %s", status, body)
+		}
+	})
+}
+
+// requireFunctionHandlers fails the check when the provider does not
+// implement the function CRUD handlers, a provider can not do without them.
+func requireFunctionHandlers(t *testing.T, handlers types.FaaSHandlers) {
+	t.Helper()
+
+	required := map[string]http.HandlerFunc{
+		"DeployHandler":  handlers.DeployHandler,
+		"UpdateHandler":  handlers.UpdateHandler,
+		"DeleteHandler":  handlers.DeleteHandler,
+		"FunctionReader": handlers.FunctionReader,
+		"ReplicaReader":  handlers.ReplicaReader,
+	}
+	for name, handler := range required {
+		if handler == nil {
+			t.Fatalf("%s is required", name)
+		}
+	}
+}
+
+func (c *conformance) deployment(name, namespace string) types.FunctionDeployment {
+	return types.FunctionDeployment{
+		Service:     name,
+		Image:       images.Alpine,
+		Namespace:   namespace,
+		EnvProcess:  "sha512sum",
+		EnvVars:     map[string]string{"revision": "1"},
+		Labels:      &map[string]string{"conformance": "true"},
+		Annotations: &map[string]string{"com.openfaas.conformance": "lifecycle"},
+	}
+}
+
+func (c *conformance) deploy(t *testing.T, method string, deployment types.FunctionDeployment) {
+	t.Helper()
+
+	status, body := c.do(t, method, "/system/functions", nil, deployment)
+	if status != http.StatusOK && status != http.StatusAccepted {
+		t.Fatalf("%s of %s got %d, wanted %d or %d: %s",
+			method, deployment.Service, status, http.StatusOK, http.StatusAccepted, body)
+	}
+}
+
+// cleanup deletes the function when a check stopped early
+func (c *conformance) cleanup(deployment types.FunctionDeployment) {
+	req := types.DeleteFunctionRequest{FunctionName: deployment.Service}
+	c.quietly(http.MethodDelete, "/system/functions", namespaceQuery(deployment.Namespace), req)
+}
+
+// quietly sends a clean up request, the outcome is not checked
+func (c *conformance) quietly(method, path string, query url.Values, body interface{}) {
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequest(method, c.baseURL+path+"?"+query.Encode(), bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	if res, err := c.client.Do(req); err == nil {
+		res.Body.Close()
+	}
+}
+
+func (c *conformance) delete(t *testing.T, name, namespace string) (int, []byte) {
+	t.Helper()
+
+	return c.do(t, http.MethodDelete, "/system/functions", namespaceQuery(namespace), types.DeleteFunctionRequest{FunctionName: name})
+}
+
+func (c *conformance) describe(t *testing.T, name, namespace string) (types.FunctionStatus, int) {
+	t.Helper()
+
+	var fn types.FunctionStatus
+	status, body := c.do(t, http.MethodGet, path.Join("/system/function", name), namespaceQuery(namespace), nil)
+	if status != http.StatusOK {
+		return fn, status
+	}
+	if err := json.Unmarshal(body, &fn); err != nil {
+		t.Fatalf("invalid function status %q: %s", body, err)
+	}
+	return fn, status
+}
+
+// waitForDescribe waits until describe reports the deployment
+func (c *conformance) waitForDescribe(t *testing.T, deployment types.FunctionDeployment) {
+	t.Helper()
+
+	c.eventually(t, func() error {
+		fn, status := c.describe(t, deployment.Service, deployment.Namespace)
+		if status != http.StatusOK {
+			return fmt.Errorf("describe got %d, wanted %d", status, http.StatusOK)
+		}
+		return compareDeployment(fn, deployment)
+	})
+}
+
+// compareDeployment returns an error for the first field of the deployment
+// that the function status does not report.
+func compareDeployment(fn types.FunctionStatus, deployment types.FunctionDeployment) error {
+	switch {
+	case fn.Name != deployment.Service:
+		return fmt.Errorf("got name %q, wanted %q", fn.Name, deployment.Service)
+	case fn.Image != deployment.Image:
+		return fmt.Errorf("got image %q, wanted %q", fn.Image, deployment.Image)
+	case deployment.Namespace != "" && fn.Namespace != deployment.Namespace:
+		return fmt.Errorf("got namespace %q, wanted %q", fn.Namespace, deployment.Namespace)
+	case fn.EnvProcess != deployment.EnvProcess:
+		return fmt.Errorf("got envProcess %q, wanted %q", fn.EnvProcess, deployment.EnvProcess)
+	}
+
+	fields := []struct {
+		name        string
+		got, wanted map[string]string
+	}{
+		{name: "envVars", got: fn.EnvVars, wanted: deployment.EnvVars},
+		{name: "labels", got: derefMap(fn.Labels), wanted: derefMap(deployment.Labels)},
+		{name: "annotations", got: derefMap(fn.Annotations), wanted: derefMap(deployment.Annotations)},
+	}
+	for _, f := range fields {
+		for k, v := range f.wanted {
+			if got, ok := f.got[k]; !ok || got != v {
+				return fmt.Errorf("got %s %v, wanted %s=%s", f.name, f.got, k, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+func derefMap(m *map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	return *m
+}
+
+func (c *conformance) list(t *testing.T, namespace string) []types.FunctionStatus {
+	t.Helper()
+
+	status, body := c.do(t, http.MethodGet, "/system/functions", namespaceQuery(namespace), nil)
+	if status != http.StatusOK {
+		t.Fatalf("list got %d, wanted %d: %s", status, http.StatusOK, body)
+	}
+
+	var functions []types.FunctionStatus
+	if err := json.Unmarshal(body, &functions); err != nil {
+		t.Fatalf("invalid function list %q: %s", body, err)
+	}
+	return functions
+}
+
+// listSecret reports whether the secret is listed, the list may not return
+// secret values.
+func (c *conformance) listSecret(t *testing.T, namespace, name string) bool {
+	t.Helper()
+
+	status, body := c.do(t, http.MethodGet, "/system/secrets", namespaceQuery(namespace), nil)
+	if status != http.StatusOK {
+		t.Fatalf("list secrets got %d, wanted %d: %s", status, http.StatusOK, body)
+	}
+
+	var secrets []types.Secret
+	if err := json.Unmarshal(body, &secrets); err != nil {
+		t.Fatalf("invalid secret list %q: %s", body, err)
+	}
+
+	found := false
+	for _, s := range secrets {
+		if s.Value != "" || len(s.RawValue) > 0 {
+			t.Fatalf("secret list returned the value of %s", s.Name)
+		}
+		found = found || s.Name == name
+	}
+	return found
+}
+
+// eventually retries check until it succeeds or the timeout passes
+func (c *conformance) eventually(t *testing.T, check func() error) {
+	t.Helper()
+
+	deadline := time.Now().Add(c.opts.Timeout)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("after %s: %s", c.opts.Timeout, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// do sends the request with the JSON encoded body and returns the status
+// and response body.
+func (c *conformance) do(t *testing.T, method, path string, query url.Values, body interface{}) (int, []byte) {
+	t.Helper()
+
+	res := c.request(t, context.Background(), method, path, query, body)
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("%s %s: %s", method, path, err)
+	}
+	return res.StatusCode, data
+}
+
+func (c *conformance) request(t *testing.T, ctx context.Context, method, path string, query url.Values, body interface{}) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	uri := c.baseURL + path
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %s", method, path, err)
+	}
+	return res
+}
+
+func namespaceQuery(namespace string) url.Values {
+	query := url.Values{}
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+	return query
+}
@@ -0,0 +1,382 @@
+package certifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openfaas/faas-provider/logs"
+	"github.com/openfaas/faas-provider/types"
+)
+
+const memoryDefaultNamespace = "openfaas-fn"
+
+// memoryProvider is a provider that keeps its functions and secrets in maps,
+// written like the handlers of a real provider.
+type memoryProvider struct {
+	mu         sync.Mutex
+	namespaces []string
+	functions  map[string]map[string]types.FunctionStatus
+	secrets    map[string]map[string]types.Secret
+}
+
+func newMemoryProvider(namespaces ...string) *memoryProvider {
+	p := &memoryProvider{
+		namespaces: namespaces,
+		functions:  map[string]map[string]types.FunctionStatus{},
+		secrets:    map[string]map[string]types.Secret{},
+	}
+	for _, ns := range namespaces {
+		p.functions[ns] = map[string]types.FunctionStatus{}
+		p.secrets[ns] = map[string]types.Secret{}
+	}
+	return p
+}
+
+func (p *memoryProvider) handlers() types.FaaSHandlers {
+	return types.FaaSHandlers{
+		FunctionReader:       p.list,
+		DeployHandler:        p.deploy(false),
+		UpdateHandler:        p.deploy(true),
+		DeleteHandler:        p.delete,
+		ReplicaReader:        p.describe,
+		ReplicaUpdater:       p.scale,
+		SecretHandler:        p.secret,
+		LogHandler:           logs.NewLogHandlerFunc(p, time.Second),
+		InfoHandler:          p.info,
+		ListNamespaceHandler: p.listNamespaces,
+	}
+}
+
+// namespace returns the functions of the requested namespace, nil when the
+// namespace does not exist
+func (p *memoryProvider) namespace(ns string) map[string]types.FunctionStatus {
+	if ns == "" {
+		ns = memoryDefaultNamespace
+	}
+	return p.functions[ns]
+}
+
+func (p *memoryProvider) list(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	functions := p.namespace(r.URL.Query().Get("namespace"))
+	if functions == nil {
+		http.Error(w, "unknown namespace", http.StatusBadRequest)
+		return
+	}
+
+	list := []types.FunctionStatus{}
+	for _, fn := range functions {
+		list = append(list, fn)
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+func (p *memoryProvider) deploy(update bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.FunctionDeployment
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		functions := p.namespace(req.Namespace)
+		if functions == nil {
+			http.Error(w, "unknown namespace", http.StatusBadRequest)
+			return
+		}
+		existing, exists := functions[req.Service]
+		if update && !exists {
+			http.Error(w, "function not found", http.StatusNotFound)
+			return
+		}
+
+		replicas := uint64(1)
+		if exists {
+			replicas = existing.Replicas
+		}
+		functions[req.Service] = types.FunctionStatus{
+			Name:        req.Service,
+			Image:       req.Image,
+			Namespace:   req.Namespace,
+			EnvProcess:  req.EnvProcess,
+			EnvVars:     req.EnvVars,
+			Labels:      req.Labels,
+			Annotations: req.Annotations,
+			Secrets:     req.Secrets,
+			Replicas:    replicas,
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (p *memoryProvider) delete(w http.ResponseWriter, r *http.Request) {
+	var req types.DeleteFunctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	functions := p.namespace(r.URL.Query().Get("namespace"))
+	if _, ok := functions[req.FunctionName]; !ok {
+		http.Error(w, "function not found", http.StatusNotFound)
+		return
+	}
+	delete(functions, req.FunctionName)
+}
+
+func (p *memoryProvider) describe(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fn, ok := p.namespace(r.URL.Query().Get("namespace"))[mux.Vars(r)["name"]]
+	if !ok {
+		http.Error(w, "function not found", http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(fn)
+}
+
+func (p *memoryProvider) scale(w http.ResponseWriter, r *http.Request) {
+	var req types.ScaleServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	functions := p.namespace(r.URL.Query().Get("namespace"))
+	name := mux.Vars(r)["name"]
+	fn, ok := functions[name]
+	if !ok {
+		http.Error(w, "function not found", http.StatusNotFound)
+		return
+	}
+	fn.Replicas = req.Replicas
+	functions[name] = fn
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (p *memoryProvider) secret(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if r.Method == http.MethodGet {
+		ns := r.URL.Query().Get("namespace")
+		if ns == "" {
+			ns = memoryDefaultNamespace
+		}
+		list := []types.Secret{}
+		for _, s := range p.secrets[ns] {
+			list = append(list, types.Secret{Name: s.Name, Namespace: s.Namespace})
+		}
+		_ = json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	var req types.Secret
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ns := req.Namespace
+	if ns == "" {
+		ns = memoryDefaultNamespace
+	}
+	secrets, ok := p.secrets[ns]
+	if !ok {
+		http.Error(w, "unknown namespace", http.StatusBadRequest)
+		return
+	}
+	_, exists := secrets[req.Name]
+
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			http.Error(w, "secret exists", http.StatusConflict)
+			return
+		}
+		secrets[req.Name] = req
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPut, http.MethodDelete:
+		if !exists {
+			http.Error(w, "secret not found", http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPut {
+			secrets[req.Name] = req
+		} else {
+			delete(secrets, req.Name)
+		}
+	}
+}
+
+// Query implements the logs.Requester
+func (p *memoryProvider) Query(ctx context.Context, req logs.Request) (<-chan logs.Message, error) {
+	messages := make(chan logs.Message, 1)
+	messages <- logs.Message{
+		Name:      req.Name,
+		Namespace: req.Namespace,
+		Instance:  req.Name + "-0",
+		Timestamp: time.Now(),
+		Text:      "Forked function.",
+	}
+	close(messages)
+	return messages, nil
+}
+
+func (p *memoryProvider) info(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(types.ProviderInfo{
+		Name:          "memory",
+		Orchestration: "memory",
+		Version:       &types.VersionInfo{Release: "0.1.0", SHA: "a1b2c3d"},
+	})
+}
+
+func (p *memoryProvider) listNamespaces(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(p.namespaces)
+}
+
+func Test_RunConformance(t *testing.T) {
+	p := newMemoryProvider(memoryDefaultNamespace, "staging")
+
+	RunConformance(t, p.handlers(), ConformanceOptions{
+		Namespace:  memoryDefaultNamespace,
+		Namespaces: []string{"staging"},
+		Timeout:    time.Second,
+	})
+
+	for ns, functions := range p.functions {
+		if len(functions) > 0 {
+			t.Errorf("functions left behind in %s: %v", ns, functions)
+		}
+	}
+	for ns, secrets := range p.secrets {
+		if len(secrets) > 0 {
+			t.Errorf("secrets left behind in %s: %v", ns, secrets)
+		}
+	}
+}
+
+func Test_RunConformance_SkipsNilHandlers(t *testing.T) {
+	p := newMemoryProvider(memoryDefaultNamespace)
+	handlers := p.handlers()
+	handlers.SecretHandler = nil
+	handlers.LogHandler = nil
+	handlers.ListNamespaceHandler = nil
+	handlers.ReplicaUpdater = nil
+
+	RunConformance(t, handlers, ConformanceOptions{Timeout: time.Second})
+}
+
+// brokenProviders break one rule of the provider API each, RunConformance
+// must fail with the message.
+var brokenProviders = map[string]struct {
+	handlers func(p *memoryProvider) types.FaaSHandlers
+	message  string
+}{
+	"describe finds unknown functions": {
+		handlers: func(p *memoryProvider) types.FaaSHandlers {
+			handlers := p.handlers()
+			handlers.ReplicaReader = func(w http.ResponseWriter, r *http.Request) {
+				rec := httptest.NewRecorder()
+				p.describe(rec, r)
+				if rec.Code == http.StatusNotFound {
+					_ = json.NewEncoder(w).Encode(types.FunctionStatus{Name: mux.Vars(r)["name"]})
+					return
+				}
+				w.WriteHeader(rec.Code)
+				_, _ = w.Write(rec.Body.Bytes())
+			}
+			return handlers
+		},
+		message: "describe got 200, wanted 404",
+	},
+	"delete of unknown functions succeeds": {
+		handlers: func(p *memoryProvider) types.FaaSHandlers {
+			handlers := p.handlers()
+			handlers.DeleteHandler = func(w http.ResponseWriter, r *http.Request) {
+				rec := httptest.NewRecorder()
+				p.delete(rec, r)
+				if rec.Code != http.StatusNotFound {
+					w.WriteHeader(rec.Code)
+				}
+			}
+			return handlers
+		},
+		message: "second delete got 200, wanted 404",
+	},
+	"scale loses the replicas": {
+		handlers: func(p *memoryProvider) types.FaaSHandlers {
+			handlers := p.handlers()
+			handlers.ReplicaUpdater = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			}
+			return handlers
+		},
+		message: "got 1 replicas, wanted 3",
+	},
+}
+
+// Test_RunConformance_FailsBrokenProviders runs RunConformance against each
+// broken provider in a child process, a failing check can not be observed
+// from the test that runs it.
+func Test_RunConformance_FailsBrokenProviders(t *testing.T) {
+	if name := os.Getenv("CERTIFIER_BROKEN_PROVIDER"); name != "" {
+		p := newMemoryProvider(memoryDefaultNamespace)
+		RunConformance(t, brokenProviders[name].handlers(p), ConformanceOptions{
+			Namespace: memoryDefaultNamespace,
+			Timeout:   100 * time.Millisecond,
+		})
+		return
+	}
+
+	for name, broken := range brokenProviders {
+		name, broken := name, broken
+		t.Run(name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^Test_RunConformance_FailsBrokenProviders$", "-test.v")
+			cmd.Env = append(os.Environ(), "CERTIFIER_BROKEN_PROVIDER="+name)
+			out, err := cmd.CombinedOutput()
+			if err == nil {
+				t.Fatalf("RunConformance passed a provider where %s:\n%s", name, out)
+			}
+			if !strings.Contains(string(out), broken.message) {
+				t.Fatalf("got output\n%s\nwanted a failure with %q", out, broken.message)
+			}
+		})
+	}
+}
+
+func Test_Router_SetsTheFunctionName(t *testing.T) {
+	var got string
+	router := newRouter(types.FaaSHandlers{
+		ReplicaReader: func(w http.ResponseWriter, r *http.Request) {
+			got = mux.Vars(r)["name"]
+		},
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/system/function/figlet.v2?namespace=staging", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != "figlet.v2" {
+		t.Fatalf("got name %q, wanted %q", got, "figlet.v2")
+	}
+}
@@ -12,7 +12,8 @@ mkdir -p $KUBECONFIG_FOLDER_PATH
 cp `pwd`/kubeconfig $KUBECONFIG_PATH
 
 echo ">>> Installing openfaas"
-arkade install openfaas --basic-auth=false --clusterrole
+arkade install openfaas --basic-auth=false --clusterrole \
+    --set functions.imagePullPolicy=IfNotPresent
 
 kubectl create namespace certifier-test
 kubectl annotate namespace/certifier-test openfaas="1"
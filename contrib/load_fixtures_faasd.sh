@@ -0,0 +1,11 @@
+#!/bin/bash
+
+set -euo pipefail
+
+FIXTURES_DIR=${1:-build/fixtures}
+
+for f in "$FIXTURES_DIR"/*.tar
+do
+    echo "importing $f"
+    sudo ctr -n openfaas-fn images import "$f"
+done
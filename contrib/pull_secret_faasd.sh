@@ -0,0 +1,32 @@
+#!/bin/bash
+
+# Installs or removes the registry credentials for the private registry
+# tests in the docker config read by faasd.
+#
+# usage: pull_secret_faasd.sh create|delete
+
+set -euo pipefail
+
+CONFIG=/var/lib/faasd/.docker/config.json
+
+case "$1" in
+create)
+    AUTH=$(echo -n "$CERTIFIER_REGISTRY_USERNAME:$CERTIFIER_REGISTRY_PASSWORD" | base64 -w0)
+    sudo mkdir -p "$(dirname "$CONFIG")"
+    if [ -f "$CONFIG" ]; then
+        sudo cp "$CONFIG" "$CONFIG.certifier-backup"
+    fi
+    echo "{\"auths\": {\"$CERTIFIER_REGISTRY\": {\"auth\": \"$AUTH\"}}}" | sudo tee "$CONFIG" > /dev/null
+    ;;
+delete)
+    if [ -f "$CONFIG.certifier-backup" ]; then
+        sudo mv "$CONFIG.certifier-backup" "$CONFIG"
+    else
+        sudo rm -f "$CONFIG"
+    fi
+    ;;
+*)
+    echo "usage: $0 create|delete"
+    exit 1
+    ;;
+esac
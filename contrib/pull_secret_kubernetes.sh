@@ -0,0 +1,55 @@
+#!/bin/bash
+
+# Installs or removes the registry credentials for the private registry
+# tests using an image pull secret on the default service account, as
+# documented for faas-netes.
+#
+# usage: pull_secret_kubernetes.sh create|delete
+
+set -euo pipefail
+
+SECRET_NAME=certifier-registry
+
+# pull_secret_names prints the image pull secrets of the default service
+# account, one per line
+pull_secret_names() {
+    kubectl get serviceaccount default -n "$CERTIFIER_NAMESPACE" \
+        -o jsonpath='{range .imagePullSecrets[*]}{.name}{"\n"}{end}'
+}
+
+case "$1" in
+create)
+    kubectl create secret docker-registry "$SECRET_NAME" \
+        -n "$CERTIFIER_NAMESPACE" \
+        --docker-server="$CERTIFIER_REGISTRY" \
+        --docker-username="$CERTIFIER_REGISTRY_USERNAME" \
+        --docker-password="$CERTIFIER_REGISTRY_PASSWORD"
+
+    # append to the pull secrets already on the service account, a merge
+    # patch would replace them
+    if [ -z "$(pull_secret_names)" ]; then
+        patch="[{\"op\": \"add\", \"path\": \"/imagePullSecrets\", \"value\": [{\"name\": \"$SECRET_NAME\"}]}]"
+    else
+        patch="[{\"op\": \"add\", \"path\": \"/imagePullSecrets/-\", \"value\": {\"name\": \"$SECRET_NAME\"}}]"
+    fi
+    kubectl patch serviceaccount default -n "$CERTIFIER_NAMESPACE" --type=json -p "$patch"
+    ;;
+delete)
+    # remove only the entry added by create, the test guards against the
+    # list changing in between
+    index=0
+    for name in $(pull_secret_names); do
+        if [ "$name" = "$SECRET_NAME" ]; then
+            kubectl patch serviceaccount default -n "$CERTIFIER_NAMESPACE" --type=json \
+                -p "[{\"op\": \"test\", \"path\": \"/imagePullSecrets/$index/name\", \"value\": \"$SECRET_NAME\"}, {\"op\": \"remove\", \"path\": \"/imagePullSecrets/$index\"}]"
+            break
+        fi
+        index=$((index + 1))
+    done
+    kubectl delete secret "$SECRET_NAME" -n "$CERTIFIER_NAMESPACE" --ignore-not-found
+    ;;
+*)
+    echo "usage: $0 create|delete"
+    exit 1
+    ;;
+esac
@@ -0,0 +1,39 @@
+package function
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const defaultDuration = 100 * time.Millisecond
+
+// Handle keeps one CPU busy for the `duration` query parameter, the
+// `cpu_duration` env variable or 100ms, so that CPU based autoscaling can be
+// exercised.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	value := r.URL.Query().Get("duration")
+	if value == "" {
+		value = os.Getenv("cpu_duration")
+	}
+
+	duration := defaultDuration
+	if value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid duration %q", value), http.StatusBadRequest)
+			return
+		}
+		duration = parsed
+	}
+
+	sum := sha512.Sum512([]byte(r.URL.RawQuery))
+	rounds := 0
+	for start := time.Now(); time.Since(start) < duration; rounds++ {
+		sum = sha512.Sum512(sum[:])
+	}
+
+	fmt.Fprintf(w, "%d rounds in %s", rounds, duration)
+}
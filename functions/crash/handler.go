@@ -0,0 +1,14 @@
+package function
+
+import (
+	"log"
+	"net/http"
+	"os"
+)
+
+// Handle terminates the process without writing a response, the caller
+// should see the connection fail and the orchestrator restart the replica.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	log.Printf("crashing on %s %s", r.Method, r.URL.Path)
+	os.Exit(1)
+}
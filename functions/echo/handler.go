@@ -0,0 +1,38 @@
+package function
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Response is the JSON document written by the echo function.
+type Response struct {
+	Method  string              `json:"method"`
+	Path    string              `json:"path"`
+	Query   string              `json:"query"`
+	Headers map[string][]string `json:"headers"`
+	Env     map[string]string   `json:"env"`
+}
+
+// Handle reports the request headers and the process environment as JSON.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	res := Response{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.RawQuery,
+		Headers: r.Header,
+		Env:     map[string]string{},
+	}
+
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			res.Env[parts[0]] = parts[1]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,48 @@
+package function
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const defaultPath = "/tmp/certifier"
+
+// Handle writes the request body to the `path` query parameter, the
+// `write_path` env variable or /tmp/certifier and reads it back. Write errors,
+// e.g. from a read-only root filesystem, are reported with a 500.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		path = os.Getenv("write_path")
+	}
+	if path == "" {
+		path = defaultPath
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		http.Error(w, fmt.Sprintf("unable to create directory: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := ioutil.WriteFile(path, body, 0644); err != nil {
+		http.Error(w, fmt.Sprintf("unable to write file: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to read file: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,157 @@
+// Command fixture serves one of the certifier fixture handlers over HTTP on
+// port 8080, following the same contract as the OpenFaaS of-watchdog. The
+// handler is selected by the first argument, which the fixture images set in
+// their entrypoint. Like the of-watchdog, the `max_inflight` env variable
+// limits the number of concurrent requests.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	cpu "github.com/openfaas/certifier/functions/cpu"
+	crash "github.com/openfaas/certifier/functions/crash"
+	echo "github.com/openfaas/certifier/functions/echo"
+	filesystem "github.com/openfaas/certifier/functions/filesystem"
+	hash "github.com/openfaas/certifier/functions/hash"
+	logger "github.com/openfaas/certifier/functions/logger"
+	memory "github.com/openfaas/certifier/functions/memory"
+	probes "github.com/openfaas/certifier/functions/probes"
+	redirector "github.com/openfaas/certifier/functions/redirector"
+	secrets "github.com/openfaas/certifier/functions/secrets"
+	sleep "github.com/openfaas/certifier/functions/sleep"
+	status "github.com/openfaas/certifier/functions/status"
+	stream "github.com/openfaas/certifier/functions/stream"
+)
+
+// handlers maps the handler folder name, as used in stack.yml, to the
+// function implementation
+var handlers = map[string]http.HandlerFunc{
+	"cpu":        cpu.Handle,
+	"crash":      crash.Handle,
+	"echo":       echo.Handle,
+	"filesystem": filesystem.Handle,
+	"hash":       hash.Handle,
+	"logger":     logger.Handle,
+	"memory":     memory.Handle,
+	"probes":     probes.Handle,
+	"redirector": redirector.Handle,
+	"secrets":    secrets.Handle,
+	"sleep":      sleep.Handle,
+	"status":     status.Handle,
+	"stream":     stream.Handle,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalf("usage: fixture <handler>, available handlers: %v", names())
+	}
+
+	name := os.Args[1]
+	handler, ok := handlers[name]
+	if !ok {
+		log.Fatalf("unknown handler %q, available handlers: %v", name, names())
+	}
+
+	port := os.Getenv("port")
+	if port == "" {
+		port = "8080"
+	}
+
+	if limit := envInt("max_inflight"); limit > 0 {
+		handler = limitInflight(handler, limit)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "OK")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		handler(w, r)
+		log.Printf("%s %s - %s", r.Method, r.URL.Path, time.Since(start).Round(time.Millisecond))
+	})
+
+	server := &http.Server{
+		Addr:         ":" + port,
+		Handler:      mux,
+		ReadTimeout:  envDuration("read_timeout", 10*time.Second),
+		WriteTimeout: envDuration("write_timeout", 10*time.Second),
+	}
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+		<-sig
+
+		log.Printf("shutting down %s", name)
+		ctx, cancel := context.WithTimeout(context.Background(), server.WriteTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	log.Printf("fixture %s listening on %s", name, server.Addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s %q: %s", name, value, err)
+	}
+	return d
+}
+
+func envInt(name string) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("invalid %s %q: %s", name, value, err)
+	}
+	return n
+}
+
+// limitInflight rejects requests with 429 once limit requests are in
+// flight, like the of-watchdog max_inflight setting.
+func limitInflight(handler http.HandlerFunc, limit int) http.HandlerFunc {
+	slots := make(chan struct{}, limit)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			handler(w, r)
+		default:
+			http.Error(w, "Concurrent request limit exceeded. Max concurrent requests: "+strconv.Itoa(limit), http.StatusTooManyRequests)
+		}
+	}
+}
+
+func names() []string {
+	list := make([]string, 0, len(handlers))
+	for name := range handlers {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,70 @@
+package function
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Response is the JSON document written for a request body.
+type Response struct {
+	Bytes  int64  `json:"bytes"`
+	SHA512 string `json:"sha512"`
+}
+
+// Handle replies with the size and SHA-512 of the request body. With the
+// `size` query parameter it instead replies with that many bytes of Pattern,
+// their SHA-512 is in the X-Sha512 header.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	if value := r.URL.Query().Get("size"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size < 0 {
+			http.Error(w, fmt.Sprintf("invalid size %q", value), http.StatusBadRequest)
+			return
+		}
+
+		sum := sha512.New()
+		if _, err := io.CopyN(sum, Pattern(), size); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.Header().Set("X-Sha512", hex.EncodeToString(sum.Sum(nil)))
+		_, _ = io.CopyN(w, Pattern(), size)
+		return
+	}
+
+	sum := sha512.New()
+	n, err := io.Copy(sum, r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Response{Bytes: n, SHA512: hex.EncodeToString(sum.Sum(nil))})
+}
+
+// Pattern returns an endless reader of the bytes 0 to 250, repeated. The
+// period is prime so that a dropped or repeated chunk changes the hash.
+func Pattern() io.Reader {
+	return &pattern{}
+}
+
+type pattern struct {
+	next byte
+}
+
+func (p *pattern) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = p.next
+		p.next = (p.next + 1) % 251
+	}
+	return len(b), nil
+}
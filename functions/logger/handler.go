@@ -0,0 +1,29 @@
+package function
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// Handle writes each line of the request body to stderr, prefixed with
+// "stderr: ", and replies with the number of lines logged.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		fmt.Fprintf(os.Stderr, "stderr: %s\n", scanner.Text())
+		count++
+	}
+
+	fmt.Fprintf(w, "logged %d lines", count)
+}
@@ -0,0 +1,36 @@
+package function
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+const mebibyte = 1024 * 1024
+
+// Handle allocates and touches `mb` MiB of memory, read from the query or
+// the `allocate_mb` env variable, so that memory limits can be exercised.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	value := r.URL.Query().Get("mb")
+	if value == "" {
+		value = os.Getenv("allocate_mb")
+	}
+	if value == "" {
+		value = "1"
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		http.Error(w, fmt.Sprintf("invalid mb %q", value), http.StatusBadRequest)
+		return
+	}
+
+	buf := make([]byte, size*mebibyte)
+	// write to every page so the memory is actually committed
+	for i := 0; i < len(buf); i += 4096 {
+		buf[i] = 1
+	}
+
+	fmt.Fprintf(w, "allocated %d MiB", len(buf)/mebibyte)
+}
@@ -0,0 +1,77 @@
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Response is the JSON document written by the probes function.
+type Response struct {
+	Hostname string `json:"hostname"`
+	Ready    bool   `json:"ready"`
+	Healthy  bool   `json:"healthy"`
+}
+
+var (
+	started = time.Now()
+	// unhealthy is set to 1 by a request to /unhealthy
+	unhealthy int32
+)
+
+// Handle serves probe endpoints whose results can be controlled:
+//
+//	/ready      fails until `ready_after` (default 30s) after the start
+//	/healthz    fails once the replica is marked unhealthy
+//	/unhealthy  marks the replica that serves the request unhealthy
+//
+// Any other path reports the state of the replica that served it.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/ready":
+		probe(w, isReady() && isHealthy())
+	case "/healthz":
+		probe(w, isHealthy())
+	case "/unhealthy":
+		atomic.StoreInt32(&unhealthy, 1)
+		writeState(w)
+	default:
+		writeState(w)
+	}
+}
+
+func probe(w http.ResponseWriter, ok bool) {
+	if !ok {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprint(w, "OK")
+}
+
+func writeState(w http.ResponseWriter) {
+	hostname, _ := os.Hostname()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Response{
+		Hostname: hostname,
+		Ready:    isReady(),
+		Healthy:  isHealthy(),
+	})
+}
+
+func isReady() bool {
+	readyAfter := 30 * time.Second
+	if value := os.Getenv("ready_after"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			readyAfter = d
+		}
+	}
+	return time.Since(started) >= readyAfter
+}
+
+func isHealthy() bool {
+	return atomic.LoadInt32(&unhealthy) == 0
+}
@@ -0,0 +1,82 @@
+package function
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultSecretsPath = "/var/openfaas/secrets"
+
+// Secret is the size and SHA-512 of a mounted secret file.
+type Secret struct {
+	Bytes  int64  `json:"bytes"`
+	SHA512 string `json:"sha512"`
+}
+
+// Handle replies with the size and SHA-512 of every secret mounted in
+// /var/openfaas/secrets, or `secrets_path`, as a JSON object keyed by the
+// secret name. The values are hashed so that binary secrets can be compared
+// byte for byte without being echoed.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	dir := os.Getenv("secrets_path")
+	if dir == "" {
+		dir = defaultSecretsPath
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	secrets := map[string]Secret{}
+	for _, entry := range entries {
+		// Kubernetes mounts the files as links into hidden ..data folders
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		secret, err := hashFile(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		secrets[entry.Name()] = secret
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(secrets)
+}
+
+func hashFile(path string) (Secret, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Secret{}, err
+	}
+	defer f.Close()
+
+	sum := sha512.New()
+	n, err := io.Copy(sum, f)
+	if err != nil {
+		return Secret{}, err
+	}
+
+	return Secret{Bytes: n, SHA512: hex.EncodeToString(sum.Sum(nil))}, nil
+}
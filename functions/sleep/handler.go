@@ -0,0 +1,39 @@
+package function
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const defaultDuration = 2 * time.Second
+
+// Handle sleeps for the `duration` query parameter, the `sleep_duration` env
+// variable or 2s, whichever is set first. The sleep stops early when the
+// caller goes away.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	value := r.URL.Query().Get("duration")
+	if value == "" {
+		value = os.Getenv("sleep_duration")
+	}
+
+	duration := defaultDuration
+	if value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid duration %q", value), http.StatusBadRequest)
+			return
+		}
+		duration = parsed
+	}
+
+	start := time.Now()
+	select {
+	case <-time.After(duration):
+	case <-r.Context().Done():
+		return
+	}
+
+	fmt.Fprintf(w, "slept %s", time.Since(start).Round(time.Millisecond))
+}
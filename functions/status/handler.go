@@ -0,0 +1,30 @@
+package function
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Handle responds with the status code from the `code` query parameter,
+// falling back to the `status_code` env variable and then 200.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	value := r.URL.Query().Get("code")
+	if value == "" {
+		value = os.Getenv("status_code")
+	}
+
+	code := http.StatusOK
+	if value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 100 || parsed > 599 {
+			http.Error(w, fmt.Sprintf("invalid status code %q", value), http.StatusBadRequest)
+			return
+		}
+		code = parsed
+	}
+
+	w.WriteHeader(code)
+	fmt.Fprintf(w, "%d", code)
+}
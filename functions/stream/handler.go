@@ -0,0 +1,49 @@
+package function
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Handle writes `lines` numbered lines (default 10), flushing each one and
+// waiting `interval` (default 100ms) between them.
+func Handle(w http.ResponseWriter, r *http.Request) {
+	lines := 10
+	if value := r.URL.Query().Get("lines"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, fmt.Sprintf("invalid lines %q", value), http.StatusBadRequest)
+			return
+		}
+		lines = parsed
+	}
+
+	interval := 100 * time.Millisecond
+	if value := r.URL.Query().Get("interval"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid interval %q", value), http.StatusBadRequest)
+			return
+		}
+		interval = parsed
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -3,6 +3,7 @@ module github.com/openfaas/certifier
 go 1.16
 
 require (
+	github.com/gorilla/mux v1.8.0
 	github.com/openfaas/faas-cli v0.0.0-20220224114835-56b1a7db771a
 	github.com/openfaas/faas-provider v0.18.9
 	github.com/rakyll/hey v0.1.4
@@ -0,0 +1,227 @@
+// Package fixtures builds the certifier fixture functions described in
+// functions/stack.yml into OCI image tarballs.
+//
+// All fixtures share a single static binary, functions/fixture, which selects
+// the handler from its first argument. The binary is compiled with the local
+// Go toolchain using the vendored dependencies, so a build needs neither
+// network access nor a container runtime.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/certifier/internal/oci"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// BinaryPath is where the fixture binary is placed inside each image.
+const BinaryPath = "/usr/bin/fixture"
+
+// Options controls a fixtures build.
+type Options struct {
+	// StackFile is the path to the fixtures stack.yml
+	StackFile string
+	// OutputDir receives one <function>.tar per fixture
+	OutputDir string
+	// Arch is the GOARCH of the images, defaults to amd64
+	Arch string
+	// Filter limits the build to functions matching the wildcard
+	Filter string
+	// Registry is prefixed to the image names written to the tarballs, it
+	// should match the `-registryPrefix` of the tests, defaults to docker.io
+	Registry string
+}
+
+// Fixture is a function from the stack file.
+type Fixture struct {
+	// Name of the function in stack.yml
+	Name string
+	// Handler is the handler folder name, which is also the argument passed
+	// to the fixture binary
+	Handler string
+	// Image is the image reference without a registry prefix
+	Image string
+}
+
+// Result describes a built image tarball.
+type Result struct {
+	Fixture
+	Path string
+	// Digest is the manifest digest of the image, as reported by a registry
+	// or runtime that loaded the tarball
+	Digest string
+}
+
+// Load parses the stack file and returns the fixtures sorted by name.
+func Load(stackFile, filter string) ([]Fixture, error) {
+	services, err := stack.ParseYAMLFile(stackFile, "", filter, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", stackFile, err)
+	}
+
+	list := []Fixture{}
+	for name, fn := range services.Functions {
+		if fn.Image == "" {
+			return nil, fmt.Errorf("function %s has no image", name)
+		}
+
+		list = append(list, Fixture{
+			Name:    name,
+			Handler: path.Base(filepath.ToSlash(fn.Handler)),
+			Image:   fn.Image,
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Build compiles the fixture binary and writes an image tarball for every
+// fixture in the stack file.
+func Build(ctx context.Context, opts Options) ([]Result, error) {
+	if opts.Arch == "" {
+		opts.Arch = "amd64"
+	}
+	if opts.Registry == "" {
+		opts.Registry = "docker.io"
+	}
+
+	list, err := Load(opts.StackFile, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", opts.StackFile)
+	}
+
+	binary, err := compile(ctx, filepath.Dir(opts.StackFile), opts.Arch)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err := oci.NewLayer([]oci.File{
+		{Name: strings.TrimPrefix(BinaryPath, "/"), Mode: 0755, Data: binary},
+		// the filesystem fixture writes here, the folder must exist when
+		// the root filesystem is read-only and /tmp is a mount
+		{Name: "tmp/", Mode: 01777},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create layer: %w", err)
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, fixture := range list {
+		result, err := write(opts, fixture, layer)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write %s: %w", fixture.Name, err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func write(opts Options, fixture Fixture, layer oci.Layer) (Result, error) {
+	img := Image(fixture, layer, opts.Arch)
+	img.Ref = opts.Registry + "/" + fixture.Image
+	img.Annotations = map[string]string{oci.AnnotationCertifierImage: fixture.Image}
+
+	target := filepath.Join(opts.OutputDir, fixture.Name+".tar")
+	f, err := os.Create(target)
+	if err != nil {
+		return Result{}, err
+	}
+	defer f.Close()
+
+	if err := oci.WriteLayout(f, img); err != nil {
+		return Result{}, err
+	}
+
+	if err := f.Close(); err != nil {
+		return Result{}, err
+	}
+
+	data, err := ioutil.ReadFile(target)
+	if err != nil {
+		return Result{}, err
+	}
+
+	layout, err := oci.ReadLayout(bytes.NewReader(data))
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", target, err)
+	}
+	if len(layout.Index.Manifests) != 1 {
+		return Result{}, fmt.Errorf("%s: got %d images, wanted 1", target, len(layout.Index.Manifests))
+	}
+
+	return Result{Fixture: fixture, Path: target, Digest: layout.Index.Manifests[0].Digest}, nil
+}
+
+// Image returns the image definition of a fixture using the shared layer,
+// the reference is the image from the stack file without a registry.
+func Image(fixture Fixture, layer oci.Layer, arch string) oci.Image {
+	return oci.Image{
+		Ref: fixture.Image,
+		Config: oci.RuntimeConfig{
+			// nobody, the fixtures never need root
+			User:         "65534:65534",
+			ExposedPorts: map[string]struct{}{"8080/tcp": {}},
+			Entrypoint:   []string{BinaryPath, fixture.Handler},
+			Env:          []string{"PATH=/usr/bin:/bin"},
+			Labels: map[string]string{
+				"com.openfaas.certifier.fixture": fixture.Name,
+			},
+		},
+		Layers:   []oci.Layer{layer},
+		Platform: oci.Platform{OS: "linux", Architecture: arch},
+	}
+}
+
+// compile builds functions/fixture as a static linux binary. The flags strip
+// every path and build id so the binary only depends on the sources and the
+// Go version.
+func compile(ctx context.Context, functionsDir, arch string) ([]byte, error) {
+	tmp, err := ioutil.TempDir("", "certifier-fixture")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	output := filepath.Join(tmp, "fixture")
+	cmd := exec.CommandContext(ctx, "go", "build",
+		"-mod=vendor",
+		"-trimpath",
+		"-ldflags", "-s -w -buildid=",
+		"-o", output,
+		"./fixture",
+	)
+	cmd.Dir = functionsDir
+	cmd.Env = append(os.Environ(),
+		"CGO_ENABLED=0",
+		"GOOS=linux",
+		"GOARCH="+arch,
+		"GOFLAGS=",
+		"GOPROXY=off",
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("go build failed: %w\n%s", err, out)
+	}
+
+	return ioutil.ReadFile(output)
+}
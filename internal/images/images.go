@@ -0,0 +1,98 @@
+// Package images resolves the image references used by a certifier run.
+//
+// The checks refer to images by a short name such as functions/alpine:latest.
+// The Resolver turns that name into the reference the provider should pull,
+// applying the registry prefix, any per-image override and any pinned digest,
+// so that a run can be pointed at a mirror in an air-gapped cluster.
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Alpine is the classic watchdog image, kept only for the check of the Http_
+// variables the classic watchdog sets. Every other check deploys a fixture
+// from functions/stack.yml.
+const Alpine = "functions/alpine:latest"
+
+// Resolver maps the image names used by the checks to pullable references.
+type Resolver struct {
+	// Prefix is prepended to every image without an override, e.g. docker.io
+	// or a local mirror such as registry.local:5000
+	Prefix string `json:"-"`
+	// Overrides replaces the full reference of an image, the prefix is not
+	// applied to overridden images
+	Overrides map[string]string `json:"overrides,omitempty"`
+	// Digests pins an image to a manifest digest, e.g. sha256:...
+	Digests map[string]string `json:"digests,omitempty"`
+}
+
+// LoadResolver returns a Resolver for prefix, reading the optional overrides
+// and digests from a JSON file such as
+//
+//	{
+//	  "overrides": {"functions/alpine:latest": "mirror.local/alpine:3.15"},
+//	  "digests": {"functions/alpine:latest": "sha256:..."}
+//	}
+func LoadResolver(prefix, file string) (*Resolver, error) {
+	r := &Resolver{}
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image config: %w", err)
+		}
+
+		if err := json.Unmarshal(data, r); err != nil {
+			return nil, fmt.Errorf("unable to parse image config %s: %w", file, err)
+		}
+	}
+
+	r.Prefix = strings.TrimRight(prefix, "/")
+
+	for name, digest := range r.Digests {
+		if err := validateDigest(digest); err != nil {
+			return nil, fmt.Errorf("invalid digest for %s: %w", name, err)
+		}
+	}
+
+	return r, nil
+}
+
+// Resolve returns the reference that should be deployed for image.
+func (r *Resolver) Resolve(image string) string {
+	ref := image
+	if override, ok := r.Overrides[image]; ok {
+		ref = override
+	} else if r.Prefix != "" {
+		ref = r.Prefix + "/" + image
+	}
+
+	if digest, ok := r.Digests[image]; ok {
+		if i := strings.Index(ref, "@"); i >= 0 {
+			ref = ref[:i]
+		}
+		ref += "@" + digest
+	}
+
+	return ref
+}
+
+// Required returns the sorted image names a certifier run deploys, given the
+// fixture images from functions/stack.yml.
+func Required(fixtureImages []string) []string {
+	set := map[string]bool{Alpine: true}
+	for _, image := range fixtureImages {
+		set[image] = true
+	}
+
+	list := make([]string, 0, len(set))
+	for image := range set {
+		list = append(list, image)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,86 @@
+package images
+
+import "testing"
+
+func Test_Resolve(t *testing.T) {
+	r := &Resolver{
+		Prefix: "registry.local:5000",
+		Overrides: map[string]string{
+			"theaxer/redirector:latest": "mirror.local/redirector:1.0",
+		},
+		Digests: map[string]string{
+			"functions/alpine:latest": "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+		},
+	}
+
+	cases := map[string]string{
+		"openfaas/certifier-echo:latest": "registry.local:5000/openfaas/certifier-echo:latest",
+		"theaxer/redirector:latest":      "mirror.local/redirector:1.0",
+		"functions/alpine:latest":        "registry.local:5000/functions/alpine:latest@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	for image, want := range cases {
+		if got := r.Resolve(image); got != want {
+			t.Errorf("Resolve(%s) want %s, got %s", image, want, got)
+		}
+	}
+}
+
+func Test_ParseReference(t *testing.T) {
+	cases := map[string]string{
+		"alpine":                              "docker.io/library/alpine:latest",
+		"functions/alpine:latest":             "docker.io/functions/alpine:latest",
+		"index.docker.io/functions/alpine":    "docker.io/functions/alpine:latest",
+		"localhost/alpine":                    "localhost/alpine:latest",
+		"registry.local:5000/team/alpine:3.1": "registry.local:5000/team/alpine:3.1",
+		"ghcr.io/openfaas/certifier-echo@sha256:1111111111111111111111111111111111111111111111111111111111111111": "ghcr.io/openfaas/certifier-echo@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+	}
+
+	for input, want := range cases {
+		ref, err := ParseReference(input)
+		if err != nil {
+			t.Errorf("ParseReference(%s) unexpected error: %s", input, err)
+			continue
+		}
+		if ref.String() != want {
+			t.Errorf("ParseReference(%s) want %s, got %s", input, want, ref.String())
+		}
+	}
+
+	for _, invalid := range []string{"", "Functions/Alpine", "alpine:", "alpine@sha256:abc"} {
+		if _, err := ParseReference(invalid); err == nil {
+			t.Errorf("ParseReference(%q) want error", invalid)
+		}
+	}
+}
+
+func Test_Compare(t *testing.T) {
+	digest := "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	cases := []struct {
+		want, got string
+		mode      string
+		ok        bool
+	}{
+		{"functions/alpine:latest", "functions/alpine:latest", CompareStrict, true},
+		{"functions/alpine:latest", "docker.io/functions/alpine:latest", CompareStrict, false},
+		{"functions/alpine:latest", "docker.io/functions/alpine:latest", CompareNormalized, true},
+		{"functions/alpine", "docker.io/functions/alpine:latest", CompareNormalized, true},
+		{"alpine:3.15", "docker.io/library/alpine:3.15", CompareNormalized, true},
+		{"functions/alpine:latest", "docker.io/functions/alpine:3.15", CompareNormalized, false},
+		{"functions/alpine:latest", "docker.io/functions/alpine@" + digest, CompareNormalized, false},
+		{"functions/alpine@" + digest, "docker.io/functions/alpine@" + digest, CompareNormalized, true},
+		{"functions/alpine:latest@" + digest, "docker.io/functions/alpine@" + digest, CompareNormalized, true},
+		{"functions/alpine:latest@" + digest, "docker.io/functions/alpine:latest", CompareNormalized, false},
+		{"functions/alpine:latest@" + digest, "docker.io/functions/alpine:3.15@" + digest, CompareNormalized, false},
+	}
+
+	for _, tc := range cases {
+		err := Compare(tc.want, tc.got, tc.mode)
+		if tc.ok && err != nil {
+			t.Errorf("Compare(%s, %s, %s) unexpected error: %s", tc.want, tc.got, tc.mode, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("Compare(%s, %s, %s) want error", tc.want, tc.got, tc.mode)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package images
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultDomain is the registry used when a reference has none
+	DefaultDomain = "docker.io"
+	// DefaultTag is the tag used when a reference has neither tag nor digest
+	DefaultTag = "latest"
+
+	legacyDefaultDomain = "index.docker.io"
+	officialRepoPrefix  = "library/"
+)
+
+// Reference is a parsed image reference.
+type Reference struct {
+	// Domain is the registry host, including the port
+	Domain string
+	// Path is the repository path within the registry
+	Path string
+	// Tag may be empty when the reference has a digest
+	Tag string
+	// Digest is the content digest, e.g. sha256:..., if present
+	Digest string
+}
+
+// ParseReference parses an image reference, filling in the same defaults as
+// the docker and containerd clients: the docker.io registry, the library/
+// namespace for official images and the latest tag.
+func ParseReference(s string) (Reference, error) {
+	ref := Reference{}
+	if s == "" {
+		return ref, fmt.Errorf("empty image reference")
+	}
+
+	name := s
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if err := validateDigest(ref.Digest); err != nil {
+			return ref, fmt.Errorf("invalid reference %q: %w", s, err)
+		}
+	}
+
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+		if ref.Tag == "" {
+			return ref, fmt.Errorf("invalid reference %q: empty tag", s)
+		}
+	}
+
+	if i := strings.Index(name, "/"); i >= 0 && isDomain(name[:i]) {
+		ref.Domain, ref.Path = name[:i], name[i+1:]
+	} else {
+		ref.Domain, ref.Path = DefaultDomain, name
+	}
+
+	if ref.Domain == legacyDefaultDomain {
+		ref.Domain = DefaultDomain
+	}
+
+	if ref.Domain == DefaultDomain && !strings.Contains(ref.Path, "/") {
+		ref.Path = officialRepoPrefix + ref.Path
+	}
+
+	if ref.Path == "" || strings.ToLower(ref.Path) != ref.Path {
+		return ref, fmt.Errorf("invalid reference %q: repository must be lowercase and not empty", s)
+	}
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = DefaultTag
+	}
+
+	return ref, nil
+}
+
+// Name returns the fully qualified repository, without tag or digest.
+func (r Reference) Name() string {
+	return r.Domain + "/" + r.Path
+}
+
+// String returns the fully qualified reference.
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Identifier returns the digest if set, otherwise the tag. This is the
+// value used in registry manifest URLs.
+func (r Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+func isDomain(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
+
+func validateDigest(digest string) error {
+	i := strings.Index(digest, ":")
+	if i <= 0 || i == len(digest)-1 {
+		return fmt.Errorf("digest %q must be in the form algorithm:hex", digest)
+	}
+
+	if digest[:i] == "sha256" && len(digest[i+1:]) != 64 {
+		return fmt.Errorf("sha256 digest %q must have 64 hex characters", digest)
+	}
+
+	for _, c := range digest[i+1:] {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return fmt.Errorf("digest %q must be lowercase hex", digest)
+		}
+	}
+	return nil
+}
+
+const (
+	// CompareStrict requires the reported reference to be identical
+	CompareStrict = "strict"
+	// CompareNormalized accepts references that are equal once registry,
+	// library and tag defaults are applied. When a digest was requested it
+	// must be reported, a tag may be omitted.
+	CompareNormalized = "normalized"
+)
+
+// Compare checks that the image reported by a provider, got, faithfully
+// reflects the requested image, want, using one of the Compare modes.
+func Compare(want, got, mode string) error {
+	if want == got {
+		return nil
+	}
+
+	if mode != CompareNormalized {
+		return fmt.Errorf("got image %s, want %s", got, want)
+	}
+
+	w, err := ParseReference(want)
+	if err != nil {
+		return err
+	}
+
+	g, err := ParseReference(got)
+	if err != nil {
+		return fmt.Errorf("provider reported an invalid image: %w", err)
+	}
+
+	if w.Name() != g.Name() {
+		return fmt.Errorf("got image repository %s, want %s", g.Name(), w.Name())
+	}
+
+	if w.Digest != "" {
+		if g.Digest != w.Digest {
+			return fmt.Errorf("got image %s, want digest %s", got, w.Digest)
+		}
+		if w.Tag != "" && g.Tag != "" && g.Tag != w.Tag {
+			return fmt.Errorf("got image tag %s, want %s", g.Tag, w.Tag)
+		}
+		return nil
+	}
+
+	if g.Tag != w.Tag {
+		return fmt.Errorf("got image %s, want tag %s", got, w.Tag)
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package load runs hey load generation and summarizes the results, so
+// that a scaling failure can be told apart from load that mostly failed.
+package load
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/certifier/internal/stats"
+	"github.com/rakyll/hey/requester"
+)
+
+// jsonOutput is a hey output template that writes the parts of the report
+// used by Summarize as JSON. The whole report can not be used, its averages
+// are NaN when every request failed.
+const jsonOutput = `{"NumRes":{{ .NumRes }},"Rps":{{ .Rps }},"StatusCodeDist":{{ jsonify .StatusCodeDist }},"ErrorDist":{{ jsonify .ErrorDist }},"Lats":{{ jsonify .Lats }}}`
+
+// Error types used by Summary.Errors
+const (
+	ErrorTimeout           = "timeout"
+	ErrorConnectionRefused = "connection refused"
+	ErrorConnectionReset   = "connection reset"
+	ErrorEOF               = "eof"
+	ErrorOther             = "other"
+)
+
+// Summary is the structured result of a load run.
+type Summary struct {
+	// Requests is the number of requests sent, including failed ones
+	Requests int64 `json:"requests"`
+	// RPS is the achieved rate of requests per second
+	RPS float64 `json:"rps"`
+	// StatusCodes counts the responses by status code
+	StatusCodes map[int]int `json:"statusCodes"`
+	// Errors counts the requests that got no response, by error type
+	Errors map[string]int `json:"errors"`
+	// Latencies of the requests that got a response
+	Latencies stats.Latencies `json:"latencies"`
+}
+
+// Run is a load run in progress.
+type Run struct {
+	work *requester.Work
+	out  bytes.Buffer
+	done chan struct{}
+	stop sync.Once
+}
+
+// Start runs work in the background, its Output and Writer are replaced to
+// collect the results.
+func Start(work *requester.Work) *Run {
+	r := &Run{work: work, done: make(chan struct{})}
+
+	work.Output = jsonOutput
+	work.Writer = &r.out
+	work.Init()
+
+	go func() {
+		work.Run()
+		close(r.done)
+	}()
+
+	return r
+}
+
+// Stop stops the workers and returns the summary of the requests sent so
+// far.
+func (r *Run) Stop() (Summary, error) {
+	r.stop.Do(r.work.Stop)
+	return r.Wait()
+}
+
+// Wait blocks until all requests are sent or the run is stopped.
+func (r *Run) Wait() (Summary, error) {
+	<-r.done
+
+	var report requester.Report
+	if err := json.Unmarshal(r.out.Bytes(), &report); err != nil {
+		return Summary{}, fmt.Errorf("unable to parse the load report: %w", err)
+	}
+	return Summarize(report), nil
+}
+
+// Summarize converts a hey report.
+func Summarize(report requester.Report) Summary {
+	s := Summary{
+		Requests:    report.NumRes,
+		RPS:         report.Rps,
+		StatusCodes: map[int]int{},
+		Errors:      map[string]int{},
+	}
+
+	for code, count := range report.StatusCodeDist {
+		s.StatusCodes[code] = count
+	}
+
+	for message, count := range report.ErrorDist {
+		s.Errors[ErrorType(message)] += count
+	}
+
+	latencies := make([]time.Duration, 0, len(report.Lats))
+	for _, seconds := range report.Lats {
+		latencies = append(latencies, time.Duration(seconds*float64(time.Second)))
+	}
+	s.Latencies = stats.Summarize(latencies)
+
+	return s
+}
+
+// ErrorType classifies the error message of a failed request.
+func ErrorType(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "timeout"), strings.Contains(lower, "deadline exceeded"):
+		return ErrorTimeout
+	case strings.Contains(lower, "connection refused"):
+		return ErrorConnectionRefused
+	case strings.Contains(lower, "connection reset"), strings.Contains(lower, "broken pipe"):
+		return ErrorConnectionReset
+	case strings.HasSuffix(lower, "eof"):
+		return ErrorEOF
+	default:
+		return ErrorOther
+	}
+}
+
+// Count returns the number of responses with a status code in [from, to].
+func (s Summary) Count(from, to int) int {
+	n := 0
+	for code, count := range s.StatusCodes {
+		if code >= from && code <= to {
+			n += count
+		}
+	}
+	return n
+}
+
+func (s Summary) String() string {
+	var codes []int
+	for code := range s.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d requests at %.1f rps", s.Requests, s.RPS)
+	for _, code := range codes {
+		fmt.Fprintf(&b, ", %d: %d", code, s.StatusCodes[code])
+	}
+
+	var types []string
+	for errorType := range s.Errors {
+		types = append(types, errorType)
+	}
+	sort.Strings(types)
+	for _, errorType := range types {
+		fmt.Fprintf(&b, ", %s: %d", errorType, s.Errors[errorType])
+	}
+
+	fmt.Fprintf(&b, ", latency p50 %s, p95 %s, p99 %s", s.Latencies.P50, s.Latencies.P95, s.Latencies.P99)
+	return b.String()
+}
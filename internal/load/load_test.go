@@ -0,0 +1,103 @@
+package load
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rakyll/hey/requester"
+)
+
+func Test_Run(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	for _, c := range []struct {
+		query string
+		code  int
+	}{{"", http.StatusOK}, {"fail=1", http.StatusBadGateway}} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"?"+c.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		run := Start(&requester.Work{Request: req, N: 20, C: 2, Timeout: 10})
+		summary, err := run.Wait()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if summary.Requests != 20 || summary.StatusCodes[c.code] != 20 {
+			t.Fatalf("want 20 requests with %d, got %s", c.code, summary)
+		}
+		if summary.Latencies.Count != 20 {
+			t.Fatalf("want 20 latencies, got %d", summary.Latencies.Count)
+		}
+	}
+}
+
+func Test_ErrorType(t *testing.T) {
+	cases := map[string]string{
+		`Post "http://127.0.0.1:8080/function/f": context deadline exceeded (Client.Timeout exceeded while awaiting headers)`: ErrorTimeout,
+		`Post "http://127.0.0.1:8080/function/f": dial tcp 127.0.0.1:8080: connect: connection refused`:                       ErrorConnectionRefused,
+		`Post "http://127.0.0.1:8080/function/f": read tcp 127.0.0.1:1->127.0.0.1:8080: read: connection reset by peer`:       ErrorConnectionReset,
+		`Post "http://127.0.0.1:8080/function/f": EOF`:                                                                        ErrorEOF,
+		`something else`: ErrorOther,
+	}
+
+	for message, want := range cases {
+		if got := ErrorType(message); got != want {
+			t.Errorf("ErrorType(%q) want %q, got %q", message, want, got)
+		}
+	}
+}
+
+func Test_Stop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := Start(&requester.Work{Request: req, N: 1000000, C: 2, QPS: 50, Timeout: 10})
+	summary, err := run.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Requests >= 1000000 {
+		t.Fatalf("Stop did not stop the run, got %s", summary)
+	}
+
+	// stopping twice must not block
+	if _, err := run.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Run_AllRequestsFail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := Start(&requester.Work{Request: req, N: 10, C: 2, Timeout: 10}).Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Errors[ErrorConnectionRefused] != 10 {
+		t.Fatalf("want 10 refused connections, got %s", summary)
+	}
+}
@@ -0,0 +1,407 @@
+// Package oci writes OCI image layout tarballs without requiring a container
+// runtime. The output is byte-for-byte reproducible for the same inputs so
+// that fixture images can be built offline and compared between runs.
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	MediaTypeIndex    = "application/vnd.oci.image.index.v1+json"
+	MediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeConfig   = "application/vnd.oci.image.config.v1+json"
+	MediaTypeLayer    = "application/vnd.oci.image.layer.v1.tar+gzip"
+
+	// AnnotationRefName is the OCI annotation holding the image reference
+	// of a manifest in index.json.
+	AnnotationRefName = "org.opencontainers.image.ref.name"
+	// AnnotationImageName is the annotation containerd's `ctr images import`
+	// uses for the full image name.
+	AnnotationImageName = "io.containerd.image.name"
+	// AnnotationCertifierImage records the unprefixed image name used by the
+	// checks, e.g. functions/alpine:latest, so it can be re-tagged on push.
+	AnnotationCertifierImage = "com.openfaas.certifier.image"
+)
+
+// Epoch is the timestamp written to every tar header and image config, this
+// keeps the digests stable between builds.
+var Epoch = time.Unix(0, 0).UTC()
+
+// Descriptor references a blob by its digest.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *Platform         `json:"platform,omitempty"`
+}
+
+// Platform describes the OS and architecture of an image.
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// Manifest is an OCI image manifest.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// Index is the OCI image index stored as index.json in the layout.
+type Index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+// ImageConfig is the subset of the OCI image configuration used by the
+// certifier fixtures.
+type ImageConfig struct {
+	Created      *time.Time    `json:"created,omitempty"`
+	Architecture string        `json:"architecture"`
+	OS           string        `json:"os"`
+	Config       RuntimeConfig `json:"config"`
+	RootFS       RootFS        `json:"rootfs"`
+}
+
+// RuntimeConfig holds the execution parameters of an image.
+type RuntimeConfig struct {
+	User         string              `json:"User,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+}
+
+// RootFS lists the uncompressed layer digests of an image.
+type RootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+// File is a single entry in a layer.
+type File struct {
+	Name string
+	Mode int64
+	Data []byte
+}
+
+// Layer is a gzip compressed layer tarball and its digests.
+type Layer struct {
+	// Data is the compressed layer
+	Data []byte
+	// DiffID is the digest of the uncompressed tarball
+	DiffID string
+}
+
+// Image is a single image to be written to a layout.
+type Image struct {
+	// Ref is the full image reference, e.g. docker.io/functions/alpine:latest
+	Ref    string
+	Config RuntimeConfig
+	Layers []Layer
+	// Platform defaults to linux/amd64 when empty
+	Platform Platform
+	// Annotations are added to the manifest descriptor in index.json
+	Annotations map[string]string
+}
+
+// Layout is an OCI image layout held in memory.
+type Layout struct {
+	Index Index
+	// Blobs maps the digest to the blob content
+	Blobs map[string][]byte
+}
+
+// Digest returns the sha256 digest of data in the OCI digest format.
+func Digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+// NewLayer builds a reproducible layer from files. Parent directories are
+// created automatically and entries are sorted by name.
+func NewLayer(files []File) (Layer, error) {
+	entries := map[string]File{}
+	for _, f := range files {
+		entries[f.Name] = f
+		for dir := parentDir(f.Name); dir != ""; dir = parentDir(dir) {
+			if _, ok := entries[dir+"/"]; !ok {
+				entries[dir+"/"] = File{Name: dir + "/", Mode: 0755}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var raw bytes.Buffer
+	tw := tar.NewWriter(&raw)
+	for _, name := range names {
+		f := entries[name]
+		hdr := &tar.Header{
+			Name:    f.Name,
+			Mode:    f.Mode,
+			ModTime: Epoch,
+			Format:  tar.FormatPAX,
+		}
+		if name[len(name)-1] == '/' {
+			hdr.Typeflag = tar.TypeDir
+		} else {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(f.Data))
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return Layer{}, err
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return Layer{}, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return Layer{}, err
+	}
+
+	var compressed bytes.Buffer
+	// the zero gzip.Header omits the name and mtime, keeping the output stable
+	gw, err := gzip.NewWriterLevel(&compressed, gzip.BestCompression)
+	if err != nil {
+		return Layer{}, err
+	}
+	if _, err := gw.Write(raw.Bytes()); err != nil {
+		return Layer{}, err
+	}
+	if err := gw.Close(); err != nil {
+		return Layer{}, err
+	}
+
+	return Layer{Data: compressed.Bytes(), DiffID: Digest(raw.Bytes())}, nil
+}
+
+// WriteLayout writes images as an OCI image layout tarball to w.
+func WriteLayout(w io.Writer, images ...Image) error {
+	layout := &Layout{
+		Index: Index{SchemaVersion: 2, MediaType: MediaTypeIndex},
+		Blobs: map[string][]byte{},
+	}
+
+	for _, img := range images {
+		desc, err := addImage(layout.Blobs, img)
+		if err != nil {
+			return fmt.Errorf("image %s: %w", img.Ref, err)
+		}
+		layout.Index.Manifests = append(layout.Index.Manifests, desc)
+	}
+
+	return layout.Write(w)
+}
+
+// Write writes the layout as a tarball, blobs are written in digest order.
+func (l *Layout) Write(w io.Writer) error {
+	indexBytes, err := json.Marshal(l.Index)
+	if err != nil {
+		return err
+	}
+
+	digests := make([]string, 0, len(l.Blobs))
+	for digest := range l.Blobs {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+
+	tw := tar.NewWriter(w)
+	write := func(name string, data []byte) error {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			ModTime:  Epoch,
+			Format:   tar.FormatPAX,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	}
+
+	if err := write("oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`)); err != nil {
+		return err
+	}
+	if err := write("index.json", indexBytes); err != nil {
+		return err
+	}
+	for _, digest := range digests {
+		if err := write(blobPath(digest), l.Blobs[digest]); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// ReadLayout reads an OCI image layout tarball and verifies the blob digests.
+func ReadLayout(r io.Reader) (*Layout, error) {
+	layout := &Layout{Blobs: map[string][]byte{}}
+	foundIndex := false
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := strings.TrimPrefix(hdr.Name, "./")
+		switch {
+		case name == "index.json":
+			if err := json.NewDecoder(tr).Decode(&layout.Index); err != nil {
+				return nil, fmt.Errorf("invalid index.json: %w", err)
+			}
+			foundIndex = true
+		case strings.HasPrefix(name, "blobs/"):
+			parts := strings.Split(name, "/")
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("unexpected blob path %s", name)
+			}
+
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+
+			digest := parts[1] + ":" + parts[2]
+			if parts[1] == "sha256" && Digest(data) != digest {
+				return nil, fmt.Errorf("blob %s does not match its digest", name)
+			}
+			layout.Blobs[digest] = data
+		}
+	}
+
+	if !foundIndex {
+		return nil, fmt.Errorf("not an OCI image layout, index.json is missing")
+	}
+
+	return layout, nil
+}
+
+// Manifest returns the parsed manifest referenced by desc.
+func (l *Layout) Manifest(desc Descriptor) (Manifest, error) {
+	manifest := Manifest{}
+	data, ok := l.Blobs[desc.Digest]
+	if !ok {
+		return manifest, fmt.Errorf("manifest %s not found in layout", desc.Digest)
+	}
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid manifest %s: %w", desc.Digest, err)
+	}
+	return manifest, nil
+}
+
+func addImage(blobs map[string][]byte, img Image) (Descriptor, error) {
+	platform := img.Platform
+	if platform.OS == "" {
+		platform.OS = "linux"
+	}
+	if platform.Architecture == "" {
+		platform.Architecture = "amd64"
+	}
+
+	created := Epoch
+	cfg := ImageConfig{
+		Created:      &created,
+		Architecture: platform.Architecture,
+		OS:           platform.OS,
+		Config:       img.Config,
+		RootFS:       RootFS{Type: "layers", DiffIDs: []string{}},
+	}
+
+	manifest := Manifest{SchemaVersion: 2, MediaType: MediaTypeManifest, Layers: []Descriptor{}}
+	for _, layer := range img.Layers {
+		cfg.RootFS.DiffIDs = append(cfg.RootFS.DiffIDs, layer.DiffID)
+		manifest.Layers = append(manifest.Layers, addBlob(blobs, MediaTypeLayer, layer.Data))
+	}
+
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	manifest.Config = addBlob(blobs, MediaTypeConfig, cfgBytes)
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	desc := addBlob(blobs, MediaTypeManifest, manifestBytes)
+	desc.Platform = &platform
+	desc.Annotations = map[string]string{
+		AnnotationImageName: img.Ref,
+		AnnotationRefName:   refName(img.Ref),
+	}
+	for k, v := range img.Annotations {
+		desc.Annotations[k] = v
+	}
+	return desc, nil
+}
+
+func addBlob(blobs map[string][]byte, mediaType string, data []byte) Descriptor {
+	digest := Digest(data)
+	blobs[digest] = data
+	return Descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(data))}
+}
+
+// refName returns the tag of ref, which is what the OCI spec expects in the
+// ref.name annotation.
+func refName(ref string) string {
+	for i := len(ref) - 1; i >= 0; i-- {
+		switch ref[i] {
+		case ':':
+			return ref[i+1:]
+		case '/':
+			return "latest"
+		}
+	}
+	return "latest"
+}
+
+func blobPath(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
+
+func parentDir(name string) string {
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '/' && i != len(name)-1 {
+			return name[:i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func Test_WriteLayout_IsReproducible(t *testing.T) {
+	build := func() []byte {
+		layer, err := NewLayer([]File{
+			{Name: "usr/bin/fixture", Mode: 0755, Data: []byte("binary")},
+			{Name: "tmp/", Mode: 01777},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		img := Image{
+			Ref:    "openfaas/certifier-echo:latest",
+			Config: RuntimeConfig{Entrypoint: []string{"/usr/bin/fixture", "echo"}},
+			Layers: []Layer{layer},
+		}
+		if err := WriteLayout(&out, img); err != nil {
+			t.Fatal(err)
+		}
+		return out.Bytes()
+	}
+
+	first, second := build(), build()
+	if !bytes.Equal(first, second) {
+		t.Fatalf("want identical layouts, got digests %s and %s", Digest(first), Digest(second))
+	}
+}
+
+func Test_WriteLayout_IndexAnnotations(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteLayout(&out, Image{Ref: "registry:5000/openfaas/certifier-echo:0.1.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(&out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			t.Fatal("index.json not found")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name != "index.json" {
+			continue
+		}
+
+		var index Index
+		if err := json.NewDecoder(tr).Decode(&index); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(index.Manifests) != 1 {
+			t.Fatalf("want 1 manifest, got %d", len(index.Manifests))
+		}
+
+		annotations := index.Manifests[0].Annotations
+		if got := annotations[AnnotationRefName]; got != "0.1.0" {
+			t.Fatalf("want ref name 0.1.0, got %q", got)
+		}
+		if got := annotations[AnnotationImageName]; got != "registry:5000/openfaas/certifier-echo:0.1.0" {
+			t.Fatalf("want full image name, got %q", got)
+		}
+		return
+	}
+}
@@ -0,0 +1,455 @@
+// Package registry is a minimal client for the OCI distribution API, enough
+// to copy the certifier images between a registry and an OCI image layout.
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/openfaas/certifier/internal/images"
+	"github.com/openfaas/certifier/internal/oci"
+)
+
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+var manifestAccept = strings.Join([]string{
+	oci.MediaTypeManifest,
+	oci.MediaTypeIndex,
+	MediaTypeDockerManifest,
+	MediaTypeDockerManifestList,
+}, ", ")
+
+// Client copies images from and to registries.
+type Client struct {
+	// HTTP is the client used for all requests, defaults to http.DefaultClient
+	HTTP *http.Client
+	// Username and Password are used for basic auth and token requests
+	Username string
+	Password string
+	// PlainHTTP uses http instead of https, localhost registries always use
+	// plain http
+	PlainHTTP bool
+
+	mu         sync.Mutex
+	authHeader map[string]string
+}
+
+// StatusError is returned when the registry replies with an unexpected status.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %d: %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+// Pull downloads the manifest, config and layers of ref into layout and
+// returns the descriptor of the manifest. When ref points to an index, the
+// manifest for platform is selected.
+func (c *Client) Pull(ctx context.Context, ref images.Reference, platform oci.Platform, layout *oci.Layout) (oci.Descriptor, error) {
+	data, mediaType, err := c.fetchManifest(ctx, ref, ref.Identifier())
+	if err != nil {
+		return oci.Descriptor{}, err
+	}
+
+	if mediaType == oci.MediaTypeIndex || mediaType == MediaTypeDockerManifestList {
+		index := oci.Index{}
+		if err := json.Unmarshal(data, &index); err != nil {
+			return oci.Descriptor{}, fmt.Errorf("invalid index for %s: %w", ref, err)
+		}
+
+		desc, ok := selectPlatform(index, platform)
+		if !ok {
+			return oci.Descriptor{}, fmt.Errorf("%s has no manifest for %s/%s", ref, platform.OS, platform.Architecture)
+		}
+
+		data, mediaType, err = c.fetchManifest(ctx, ref, desc.Digest)
+		if err != nil {
+			return oci.Descriptor{}, err
+		}
+	}
+
+	manifest := oci.Manifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return oci.Descriptor{}, fmt.Errorf("invalid manifest for %s: %w", ref, err)
+	}
+
+	for _, desc := range append([]oci.Descriptor{manifest.Config}, manifest.Layers...) {
+		if _, ok := layout.Blobs[desc.Digest]; ok {
+			continue
+		}
+
+		blob, err := c.fetchBlob(ctx, ref, desc.Digest)
+		if err != nil {
+			return oci.Descriptor{}, err
+		}
+		layout.Blobs[desc.Digest] = blob
+	}
+
+	digest := oci.Digest(data)
+	layout.Blobs[digest] = data
+
+	p := platform
+	return oci.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest,
+		Size:      int64(len(data)),
+		Platform:  &p,
+	}, nil
+}
+
+// Push uploads the image described by desc from layout to ref and returns
+// the digest of the pushed manifest.
+func (c *Client) Push(ctx context.Context, ref images.Reference, layout *oci.Layout, desc oci.Descriptor) (string, error) {
+	manifest, err := layout.Manifest(desc)
+	if err != nil {
+		return "", err
+	}
+
+	for _, blob := range append([]oci.Descriptor{manifest.Config}, manifest.Layers...) {
+		data, ok := layout.Blobs[blob.Digest]
+		if !ok {
+			return "", fmt.Errorf("blob %s of %s is missing from the layout", blob.Digest, ref)
+		}
+
+		if err := c.pushBlob(ctx, ref, blob.Digest, data); err != nil {
+			return "", err
+		}
+	}
+
+	mediaType := desc.MediaType
+	if mediaType == "" {
+		mediaType = oci.MediaTypeManifest
+	}
+
+	data := layout.Blobs[desc.Digest]
+	target := c.url(ref, "manifests", ref.Identifier())
+	res, err := c.do(ctx, ref, http.MethodPut, target, map[string]string{"Content-Type": mediaType}, data)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return "", statusError(res)
+	}
+
+	return oci.Digest(data), nil
+}
+
+// Digest returns the digest of the manifest, or index, that ref points to.
+func (c *Client) Digest(ctx context.Context, ref images.Reference) (string, error) {
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	target := c.url(ref, "manifests", ref.Identifier())
+	res, err := c.do(ctx, ref, http.MethodHead, target, map[string]string{"Accept": manifestAccept}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", statusError(res)
+	}
+
+	if digest := res.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	data, _, err := c.fetchManifest(ctx, ref, ref.Identifier())
+	if err != nil {
+		return "", err
+	}
+	return oci.Digest(data), nil
+}
+
+func (c *Client) fetchManifest(ctx context.Context, ref images.Reference, identifier string) ([]byte, string, error) {
+	target := c.url(ref, "manifests", identifier)
+	res, err := c.do(ctx, ref, http.MethodGet, target, map[string]string{"Accept": manifestAccept}, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", statusError(res)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mediaType := res.Header.Get("Content-Type")
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+
+	if strings.HasPrefix(identifier, "sha256:") && oci.Digest(data) != identifier {
+		return nil, "", fmt.Errorf("manifest of %s does not match digest %s", ref.Name(), identifier)
+	}
+
+	return data, mediaType, nil
+}
+
+func (c *Client) fetchBlob(ctx context.Context, ref images.Reference, digest string) ([]byte, error) {
+	res, err := c.do(ctx, ref, http.MethodGet, c.url(ref, "blobs", digest), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if oci.Digest(data) != digest {
+		return nil, fmt.Errorf("blob %s of %s does not match its digest", digest, ref.Name())
+	}
+	return data, nil
+}
+
+func (c *Client) pushBlob(ctx context.Context, ref images.Reference, digest string, data []byte) error {
+	res, err := c.do(ctx, ref, http.MethodHead, c.url(ref, "blobs", digest), nil, nil)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	res, err = c.do(ctx, ref, http.MethodPost, c.url(ref, "blobs", "uploads/"), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusAccepted {
+		return statusError(res)
+	}
+
+	location, err := res.Request.URL.Parse(res.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location: %w", err)
+	}
+
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	headers := map[string]string{"Content-Type": "application/octet-stream"}
+	res, err = c.do(ctx, ref, http.MethodPut, location.String(), headers, data)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		return statusError(res)
+	}
+	return nil
+}
+
+func (c *Client) url(ref images.Reference, kind, identifier string) string {
+	scheme := "https"
+	host := ref.Domain
+	if host == images.DefaultDomain {
+		host = "registry-1.docker.io"
+	}
+
+	if c.PlainHTTP || isLocalhost(host) {
+		scheme = "http"
+	}
+
+	return fmt.Sprintf("%s://%s/v2/%s/%s/%s", scheme, host, ref.Path, kind, identifier)
+}
+
+// do sends the request, answering a single basic or bearer auth challenge.
+// The resulting Authorization header is cached per repository.
+func (c *Client) do(ctx context.Context, ref images.Reference, method, target string, headers map[string]string, body []byte) (*http.Response, error) {
+	send := func(auth string) (*http.Response, error) {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequest(method, target, reader)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+
+		return c.client().Do(req)
+	}
+
+	key := ref.Name()
+	c.mu.Lock()
+	auth := c.authHeader[key]
+	c.mu.Unlock()
+
+	res, err := send(auth)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	challenge := res.Header.Get("WWW-Authenticate")
+	res.Body.Close()
+
+	auth, err = c.authorize(ctx, ref, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.authHeader == nil {
+		c.authHeader = map[string]string{}
+	}
+	c.authHeader[key] = auth
+	c.mu.Unlock()
+
+	return send(auth)
+}
+
+func (c *Client) authorize(ctx context.Context, ref images.Reference, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.Username == "" {
+			return "", fmt.Errorf("%s requires credentials", ref.Domain)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password)), nil
+
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", fmt.Errorf("invalid bearer realm %q", params["realm"])
+		}
+
+		query := realm.Query()
+		if params["service"] != "" {
+			query.Set("service", params["service"])
+		}
+		query.Set("scope", fmt.Sprintf("repository:%s:pull,push", ref.Path))
+		realm.RawQuery = query.Encode()
+
+		req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		req = req.WithContext(ctx)
+		if c.Username != "" {
+			req.SetBasicAuth(c.Username, c.Password)
+		}
+
+		res, err := c.client().Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return "", statusError(res)
+		}
+
+		token := struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}{}
+		if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+			return "", fmt.Errorf("invalid token response: %w", err)
+		}
+
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+	}
+
+	return "", fmt.Errorf("unsupported auth challenge %q from %s", challenge, ref.Domain)
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io"
+func parseChallenge(header string) (string, map[string]string) {
+	params := map[string]string{}
+	header = strings.TrimSpace(header)
+
+	i := strings.Index(header, " ")
+	if i < 0 {
+		return header, params
+	}
+
+	scheme, rest := header[:i], header[i+1:]
+	for _, part := range strings.Split(rest, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return scheme, params
+}
+
+func selectPlatform(index oci.Index, platform oci.Platform) (oci.Descriptor, bool) {
+	for _, desc := range index.Manifests {
+		if desc.Platform != nil && desc.Platform.OS == platform.OS && desc.Platform.Architecture == platform.Architecture {
+			return desc, true
+		}
+	}
+	return oci.Descriptor{}, false
+}
+
+func isLocalhost(host string) bool {
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	return host == "localhost" || host == "127.0.0.1"
+}
+
+func statusError(res *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	return &StatusError{
+		Method:     res.Request.Method,
+		URL:        res.Request.URL.String(),
+		StatusCode: res.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/certifier/internal/images"
+	"github.com/openfaas/certifier/internal/oci"
+	"github.com/openfaas/certifier/internal/registry/registrytest"
+)
+
+func Test_PushAndPull(t *testing.T) {
+	reg := registrytest.New("certifier", "s3cr3t")
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	layout := testLayout(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+	ref, err := images.ParseReference(host + "/mirror/certifier-echo:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	anonymous := &Client{}
+	if _, err := anonymous.Push(ctx, ref, layout, layout.Index.Manifests[0]); err == nil {
+		t.Fatal("want push without credentials to fail")
+	}
+
+	client := &Client{Username: "certifier", Password: "s3cr3t"}
+	digest, err := client.Push(ctx, ref, layout, layout.Index.Manifests[0])
+	if err != nil {
+		t.Fatalf("push failed: %s", err)
+	}
+
+	if digest != layout.Index.Manifests[0].Digest {
+		t.Fatalf("want pushed digest %s, got %s", layout.Index.Manifests[0].Digest, digest)
+	}
+
+	stored, ok := reg.Manifest("mirror/certifier-echo", "latest")
+	if !ok || stored != digest {
+		t.Fatalf("want registry to store %s for latest, got %q", digest, stored)
+	}
+
+	pulled := &oci.Layout{Blobs: map[string][]byte{}}
+	desc, err := client.Pull(ctx, ref, oci.Platform{OS: "linux", Architecture: "amd64"}, pulled)
+	if err != nil {
+		t.Fatalf("pull failed: %s", err)
+	}
+
+	if desc.Digest != digest {
+		t.Fatalf("want pulled digest %s, got %s", digest, desc.Digest)
+	}
+
+	for d, data := range layout.Blobs {
+		if !bytes.Equal(pulled.Blobs[d], data) {
+			t.Fatalf("blob %s differs after pull", d)
+		}
+	}
+}
+
+func testLayout(t *testing.T) *oci.Layout {
+	t.Helper()
+
+	layer, err := oci.NewLayer([]oci.File{{Name: "usr/bin/fixture", Mode: 0755, Data: []byte("binary")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = oci.WriteLayout(&buf, oci.Image{Ref: "docker.io/openfaas/certifier-echo:latest", Layers: []oci.Layer{layer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layout, err := oci.ReadLayout(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return layout
+}
@@ -0,0 +1,316 @@
+// Package registrytest provides an in-memory stand-in for an OCI registry.
+//
+// It implements the subset of the distribution API used by image pushes and
+// pulls, optionally protected by basic auth, so that mirroring and private
+// registry checks can run without a real registry.
+package registrytest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/openfaas/certifier/internal/oci"
+)
+
+// Registry is an in-memory registry, it implements http.Handler.
+type Registry struct {
+	// Username and Password enable basic auth when Username is not empty
+	Username string
+	Password string
+
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	uploads   map[string][]byte
+	manifests map[string]manifest
+	pulls     []Pull
+}
+
+// Pull records a manifest GET or HEAD request.
+type Pull struct {
+	Repository string
+	Reference  string
+	// Authorized is false when the request was rejected for missing or
+	// invalid credentials
+	Authorized bool
+}
+
+type manifest struct {
+	mediaType string
+	data      []byte
+}
+
+// New returns an empty registry, pass an empty username to disable auth.
+func New(username, password string) *Registry {
+	return &Registry{
+		Username:  username,
+		Password:  password,
+		blobs:     map[string][]byte{},
+		uploads:   map[string][]byte{},
+		manifests: map[string]manifest{},
+	}
+}
+
+// Pulls returns the manifest requests received so far.
+func (r *Registry) Pulls() []Pull {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Pull{}, r.pulls...)
+}
+
+// Manifest returns the digest of the manifest stored for repository and
+// reference, a tag or digest.
+func (r *Registry) Manifest(repository, reference string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.manifests[repository+"@"+reference]
+	if !ok {
+		return "", false
+	}
+	return digest(m.data), true
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/v2/" || req.URL.Path == "/v2" {
+		if r.authorize(w, req) {
+			w.WriteHeader(http.StatusOK)
+		}
+		return
+	}
+
+	if !strings.HasPrefix(req.URL.Path, "/v2/") {
+		http.NotFound(w, req)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	for _, kind := range []string{"/manifests/", "/blobs/uploads/", "/blobs/"} {
+		i := strings.LastIndex(path, kind)
+		if i <= 0 {
+			continue
+		}
+
+		repo, ref := path[:i], path[i+len(kind):]
+		authorized := r.authorize(w, req)
+		if kind == "/manifests/" && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+			r.mu.Lock()
+			r.pulls = append(r.pulls, Pull{Repository: repo, Reference: ref, Authorized: authorized})
+			r.mu.Unlock()
+		}
+
+		if !authorized {
+			return
+		}
+
+		switch kind {
+		case "/manifests/":
+			r.serveManifest(w, req, repo, ref)
+		case "/blobs/uploads/":
+			r.serveUpload(w, req, repo, ref)
+		default:
+			r.serveBlob(w, req, ref)
+		}
+		return
+	}
+
+	http.NotFound(w, req)
+}
+
+func (r *Registry) authorize(w http.ResponseWriter, req *http.Request) bool {
+	if r.Username == "" {
+		return true
+	}
+
+	username, password, ok := req.BasicAuth()
+	if ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(r.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(r.Password)) == 1 {
+		return true
+	}
+
+	w.Header().Set("WWW-Authenticate", `Basic realm="certifier"`)
+	writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+	return false
+}
+
+func (r *Registry) serveManifest(w http.ResponseWriter, req *http.Request, repo, ref string) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		r.mu.Lock()
+		m, ok := r.manifests[repo+"@"+ref]
+		r.mu.Unlock()
+
+		if !ok {
+			writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown")
+			return
+		}
+
+		w.Header().Set("Content-Type", m.mediaType)
+		w.Header().Set("Docker-Content-Digest", digest(m.data))
+		w.Header().Set("Content-Length", fmt.Sprint(len(m.data)))
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(m.data)
+		}
+
+	case http.MethodPut:
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "MANIFEST_INVALID", err.Error())
+			return
+		}
+
+		d := digest(data)
+		if strings.HasPrefix(ref, "sha256:") && ref != d {
+			writeError(w, http.StatusBadRequest, "DIGEST_INVALID", "manifest does not match digest")
+			return
+		}
+
+		m := manifest{mediaType: req.Header.Get("Content-Type"), data: data}
+		r.mu.Lock()
+		r.manifests[repo+"@"+ref] = m
+		r.manifests[repo+"@"+d] = m
+		r.mu.Unlock()
+
+		w.Header().Set("Docker-Content-Digest", d)
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/manifests/%s", repo, d))
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Registry) serveBlob(w http.ResponseWriter, req *http.Request, d string) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.mu.Lock()
+	data, ok := r.blobs[d]
+	r.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", d)
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(data)
+	}
+}
+
+func (r *Registry) serveUpload(w http.ResponseWriter, req *http.Request, repo, id string) {
+	switch {
+	case req.Method == http.MethodPost && id == "":
+		id = newID()
+		r.mu.Lock()
+		r.uploads[id] = []byte{}
+		r.mu.Unlock()
+
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", repo, id))
+		w.Header().Set("Range", "0-0")
+		w.WriteHeader(http.StatusAccepted)
+
+	case req.Method == http.MethodPatch || req.Method == http.MethodPut:
+		chunk, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BLOB_UPLOAD_INVALID", err.Error())
+			return
+		}
+
+		r.mu.Lock()
+		data, ok := r.uploads[id]
+		if ok {
+			data = append(data, chunk...)
+			r.uploads[id] = data
+		}
+		r.mu.Unlock()
+
+		if !ok {
+			writeError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "upload unknown")
+			return
+		}
+
+		if req.Method == http.MethodPatch {
+			w.Header().Set("Location", req.URL.Path)
+			w.Header().Set("Range", fmt.Sprintf("0-%d", len(data)-1))
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		want := req.URL.Query().Get("digest")
+		if digest(data) != want {
+			writeError(w, http.StatusBadRequest, "DIGEST_INVALID", "upload does not match digest")
+			return
+		}
+
+		r.mu.Lock()
+		delete(r.uploads, id)
+		r.blobs[want] = data
+		r.mu.Unlock()
+
+		w.Header().Set("Docker-Content-Digest", want)
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/%s", repo, want))
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"errors":[{"code":%q,"message":%q}]}`, code, message)
+}
+
+func digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
+
+// AddImage stores the manifest described by desc, with its config and
+// layers, under repository:tag without going through the HTTP API.
+func (r *Registry) AddImage(repository, tag string, layout *oci.Layout, desc oci.Descriptor) error {
+	m, err := layout.Manifest(desc)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, blob := range append([]oci.Descriptor{m.Config}, m.Layers...) {
+		data, ok := layout.Blobs[blob.Digest]
+		if !ok {
+			return fmt.Errorf("blob %s is missing from the layout", blob.Digest)
+		}
+		r.blobs[blob.Digest] = data
+	}
+
+	mediaType := desc.MediaType
+	if mediaType == "" {
+		mediaType = oci.MediaTypeManifest
+	}
+
+	stored := manifest{mediaType: mediaType, data: layout.Blobs[desc.Digest]}
+	r.manifests[repository+"@"+tag] = stored
+	r.manifests[repository+"@"+desc.Digest] = stored
+	return nil
+}
@@ -0,0 +1,74 @@
+// Package stats summarizes the latencies measured by the certifier.
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+)
+
+// Latencies summarizes a set of durations.
+type Latencies struct {
+	Count int
+	Min   time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Summarize returns the percentiles of values, it does not modify values.
+func Summarize(values []time.Duration) Latencies {
+	if len(values) == 0 {
+		return Latencies{}
+	}
+
+	sorted := append([]time.Duration{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return Latencies{
+		Count: len(sorted),
+		Min:   sorted[0],
+		P50:   percentile(sorted, 50),
+		P95:   percentile(sorted, 95),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// Percentile returns the nearest-rank p-th percentile of values, p is
+// between 0 and 100.
+func Percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return percentile(sorted, p)
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// MarshalJSON writes the durations as strings, e.g. "1.5s", so reports stay
+// readable.
+func (l Latencies) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"count": l.Count,
+		"min":   l.Min.String(),
+		"p50":   l.P50.String(),
+		"p95":   l.P95.String(),
+		"p99":   l.P99.String(),
+		"max":   l.Max.String(),
+	})
+}
@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Summarize(t *testing.T) {
+	var values []time.Duration
+	for i := 100; i > 0; i-- {
+		values = append(values, time.Duration(i)*time.Millisecond)
+	}
+
+	got := Summarize(values)
+	want := Latencies{
+		Count: 100,
+		Min:   time.Millisecond,
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+		Max:   100 * time.Millisecond,
+	}
+
+	if got != want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	if values[0] != 100*time.Millisecond {
+		t.Fatalf("Summarize sorted the input")
+	}
+}
+
+func Test_Percentile(t *testing.T) {
+	values := []time.Duration{3 * time.Second, time.Second, 2 * time.Second}
+
+	cases := map[float64]time.Duration{
+		0:   time.Second,
+		50:  2 * time.Second,
+		95:  3 * time.Second,
+		100: 3 * time.Second,
+	}
+
+	for p, want := range cases {
+		if got := Percentile(values, p); got != want {
+			t.Errorf("Percentile(%v) want %s, got %s", p, want, got)
+		}
+	}
+
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile of no values want 0, got %s", got)
+	}
+}
@@ -0,0 +1,236 @@
+// Package version validates the releases and commits reported by
+// /system/info and checks them against the compatibility matrix.
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dev is the release reported by builds without version information, e.g.
+// `make build` of a provider checkout.
+const Dev = "dev"
+
+var (
+	semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?$`)
+	shaPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+)
+
+// Version is a parsed semantic version.
+type Version struct {
+	Major, Minor, Patch int
+	// PreRelease is the part after the hyphen, e.g. "rc1"
+	PreRelease string
+	// Dev is true for the Dev release, which can not be ordered
+	Dev bool
+}
+
+// Parse parses a release as reported by /system/info: a semantic version,
+// optionally with a "v" prefix, or Dev.
+func Parse(release string) (Version, error) {
+	if release == Dev {
+		return Version{Dev: true}, nil
+	}
+
+	m := semverPattern.FindStringSubmatch(release)
+	if m == nil {
+		return Version{}, fmt.Errorf("release %q is neither a semantic version nor %q", release, Dev)
+	}
+
+	var v Version
+	// the pattern guarantees the numbers, only overflow can fail
+	var err error
+	if v.Major, err = strconv.Atoi(m[1]); err != nil {
+		return Version{}, fmt.Errorf("release %q: %w", release, err)
+	}
+	if v.Minor, err = strconv.Atoi(m[2]); err != nil {
+		return Version{}, fmt.Errorf("release %q: %w", release, err)
+	}
+	if v.Patch, err = strconv.Atoi(m[3]); err != nil {
+		return Version{}, fmt.Errorf("release %q: %w", release, err)
+	}
+	v.PreRelease = m[4]
+
+	return v, nil
+}
+
+// Less reports whether v orders before o. A pre-release orders before its
+// release, pre-releases of the same version are compared by their dot
+// separated identifiers as semver does.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	if v.Patch != o.Patch {
+		return v.Patch < o.Patch
+	}
+
+	switch {
+	case v.PreRelease == o.PreRelease:
+		return false
+	case v.PreRelease == "":
+		return false
+	case o.PreRelease == "":
+		return true
+	}
+	return comparePreRelease(v.PreRelease, o.PreRelease) < 0
+}
+
+// comparePreRelease compares the dot separated identifiers of two
+// pre-releases in turn: numeric identifiers numerically and before the
+// alphanumeric ones, which are compared in ASCII order, so rc.2 orders
+// before rc.10 but rc10 before rc2. A shorter list orders first when all
+// of its identifiers are equal.
+func comparePreRelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(as), len(bs))
+}
+
+func compareIdentifier(a, b string) int {
+	an, aNumeric := numeric(a)
+	bn, bNumeric := numeric(b)
+	switch {
+	case aNumeric && bNumeric:
+		return compareInt(an, bn)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// numeric parses an identifier made only of digits.
+func numeric(identifier string) (int, bool) {
+	if identifier == "" || strings.Trim(identifier, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(identifier)
+	return n, err == nil
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (v Version) String() string {
+	if v.Dev {
+		return Dev
+	}
+
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	return s
+}
+
+// ValidSHA reports whether sha looks like an abbreviated or full git commit
+// SHA.
+func ValidSHA(sha string) bool {
+	return shaPattern.MatchString(sha)
+}
+
+// Matrix is the compatibility matrix shipped with the certifier, it lists
+// the oldest provider releases and the oldest gateway each of them is
+// certified with.
+type Matrix struct {
+	Providers []Requirement `json:"providers"`
+}
+
+// Requirement is the row of a provider in the matrix.
+type Requirement struct {
+	// Name is the provider name reported by /system/info
+	Name string `json:"name"`
+	// MinRelease is the oldest supported provider release
+	MinRelease string `json:"minRelease"`
+	// MinGateway is the oldest gateway release the provider supports
+	MinGateway string `json:"minGateway"`
+}
+
+// LoadMatrix reads and validates the matrix file.
+func LoadMatrix(path string) (Matrix, error) {
+	var m Matrix
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return m, fmt.Errorf("unable to read compatibility matrix: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("unable to parse compatibility matrix %s: %w", path, err)
+	}
+
+	for _, r := range m.Providers {
+		for _, release := range []string{r.MinRelease, r.MinGateway} {
+			if v, err := Parse(release); err != nil || v.Dev {
+				return m, fmt.Errorf("compatibility matrix %s: %s needs semantic versions, got %q", path, r.Name, release)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// Check returns an error when the provider or gateway release is older
+// than the matrix allows, an empty gateway release is not checked. Providers
+// that are not in the matrix and dev builds are not checked, ok is false for
+// them.
+func (m Matrix) Check(provider, providerRelease, gatewayRelease string) (ok bool, err error) {
+	var req *Requirement
+	for i := range m.Providers {
+		if strings.EqualFold(m.Providers[i].Name, provider) {
+			req = &m.Providers[i]
+		}
+	}
+	if req == nil {
+		return false, nil
+	}
+
+	checks := []struct {
+		component, release, min string
+	}{
+		{component: provider, release: providerRelease, min: req.MinRelease},
+		{component: "gateway", release: gatewayRelease, min: req.MinGateway},
+	}
+
+	for _, c := range checks {
+		if c.release == "" && c.component == "gateway" {
+			continue
+		}
+
+		got, err := Parse(c.release)
+		if err != nil {
+			return false, err
+		}
+		if got.Dev {
+			return false, nil
+		}
+
+		min, _ := Parse(c.min)
+		if got.Less(min) {
+			return false, fmt.Errorf("%s %s is older than the minimum %s for %s", c.component, got, min, provider)
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,120 @@
+package version
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	cases := map[string]Version{
+		"0.13.4":          {Minor: 13, Patch: 4},
+		"v1.2.3":          {Major: 1, Minor: 2, Patch: 3},
+		"0.18.0-rc1":      {Minor: 18, PreRelease: "rc1"},
+		"1.0.0+build.5":   {Major: 1},
+		"2.0.0-beta.1+sh": {Major: 2, PreRelease: "beta.1"},
+		"dev":             {Dev: true},
+	}
+
+	for release, want := range cases {
+		got, err := Parse(release)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %s", release, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Parse(%q) want %+v, got %+v", release, want, got)
+		}
+	}
+
+	for _, release := range []string{"", "latest", "1.2", "01.2.3", "1.2.3.4", "v", "1.2.3-"} {
+		if _, err := Parse(release); err == nil {
+			t.Errorf("Parse(%q) want an error", release)
+		}
+	}
+}
+
+func Test_Less(t *testing.T) {
+	ordered := [][]string{
+		// alphanumeric identifiers compare in ASCII order, numeric ones as numbers
+		{"0.9.9", "0.10.0", "0.10.1-rc.2", "0.10.1-rc.10", "0.10.1-rc1", "0.10.1-rc10", "0.10.1-rc2", "0.10.1", "1.0.0"},
+		// the precedence example of the semver specification
+		{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0"},
+		{"1.0.0-1", "1.0.0-2", "1.0.0-10", "1.0.0-a"},
+	}
+	for _, releases := range ordered {
+		for i := 0; i < len(releases)-1; i++ {
+			a, _ := Parse(releases[i])
+			b, _ := Parse(releases[i+1])
+			if !a.Less(b) || b.Less(a) {
+				t.Errorf("want %s < %s", a, b)
+			}
+		}
+	}
+
+	v, _ := Parse("1.0.0")
+	if v.Less(v) {
+		t.Errorf("want %s not less than itself", v)
+	}
+}
+
+func Test_ValidSHA(t *testing.T) {
+	for _, sha := range []string{"a1b2c3d", "7d6f7c6b3e7a4b0e9c8d1f2a3b4c5d6e7f8a9b0c"} {
+		if !ValidSHA(sha) {
+			t.Errorf("want %q valid", sha)
+		}
+	}
+	for _, sha := range []string{"", "dev", "a1b2c3", "A1B2C3D", "7d6f7c6b3e7a4b0e9c8d1f2a3b4c5d6e7f8a9b0c1"} {
+		if ValidSHA(sha) {
+			t.Errorf("want %q invalid", sha)
+		}
+	}
+}
+
+func Test_Matrix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compatibility.json")
+	data := `{"providers": [{"name": "faas-netes", "minRelease": "0.13.0", "minGateway": "0.20.0"}]}`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadMatrix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		provider, release, gateway string
+		ok, err                    bool
+	}{
+		{provider: "faas-netes", release: "0.13.0", gateway: "0.20.0", ok: true},
+		{provider: "faas-netes", release: "0.14.2", gateway: "0.21.1", ok: true},
+		{provider: "faas-netes", release: "0.12.9", gateway: "0.21.1", err: true},
+		{provider: "faas-netes", release: "0.13.0", gateway: "0.19.0", err: true},
+		{provider: "faas-netes", release: "dev", gateway: "0.21.1"},
+		{provider: "faas-netes", release: "latest", gateway: "0.21.1", err: true},
+		{provider: "unknown", release: "0.1.0", gateway: "0.1.0"},
+		{provider: "faas-netes", release: "0.13.0", gateway: "", ok: true},
+		{provider: "faas-netes", release: "0.12.0", gateway: "", err: true},
+	}
+
+	for _, tc := range cases {
+		ok, err := m.Check(tc.provider, tc.release, tc.gateway)
+		if ok != tc.ok || (err != nil) != tc.err {
+			t.Errorf("Check(%s %s, gateway %s) want ok %v and error %v, got %v and %v",
+				tc.provider, tc.release, tc.gateway, tc.ok, tc.err, ok, err)
+		}
+	}
+}
+
+func Test_LoadMatrix_RejectsInvalidVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compatibility.json")
+	data := `{"providers": [{"name": "faasd", "minRelease": "dev", "minGateway": "0.20.0"}]}`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadMatrix(path); err == nil {
+		t.Fatal("want an error for a dev release in the matrix")
+	}
+}
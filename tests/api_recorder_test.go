@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/certifier/internal/stats"
+)
+
+// apiRecorder is an http.RoundTripper that records the status and latency of
+// every API call, grouped by endpoint, so that it can be passed to
+// sdk.NewClient.
+type apiRecorder struct {
+	mu    sync.Mutex
+	calls map[string][]apiCall
+}
+
+type apiCall struct {
+	duration time.Duration
+	status   int
+	failed   bool
+}
+
+// endpointSummary is the report entry of one endpoint.
+type endpointSummary struct {
+	Calls       int             `json:"calls"`
+	StatusCodes map[int]int     `json:"statusCodes"`
+	Errors      int             `json:"errors"`
+	Latencies   stats.Latencies `json:"latencies"`
+}
+
+func (r *apiRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := http.DefaultTransport.RoundTrip(req)
+
+	call := apiCall{duration: time.Since(start), failed: err != nil}
+	if res != nil {
+		call.status = res.StatusCode
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls == nil {
+		r.calls = map[string][]apiCall{}
+	}
+	key := endpoint(req.Method, req.URL.Path)
+	r.calls[key] = append(r.calls[key], call)
+
+	return res, err
+}
+
+// Summary returns the calls made so far by endpoint.
+func (r *apiRecorder) Summary() map[string]endpointSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary := map[string]endpointSummary{}
+	for key, calls := range r.calls {
+		s := endpointSummary{Calls: len(calls), StatusCodes: map[int]int{}}
+		latencies := make([]time.Duration, 0, len(calls))
+		for _, c := range calls {
+			if c.failed {
+				s.Errors++
+				continue
+			}
+			s.StatusCodes[c.status]++
+			latencies = append(latencies, c.duration)
+		}
+		s.Latencies = stats.Summarize(latencies)
+		summary[key] = s
+	}
+	return summary
+}
+
+// ServerErrors returns the number of 5xx responses.
+func (r *apiRecorder) ServerErrors() int {
+	n := 0
+	for _, s := range r.Summary() {
+		for code, count := range s.StatusCodes {
+			if code >= 500 {
+				n += count
+			}
+		}
+	}
+	return n
+}
+
+func (r *apiRecorder) String() string {
+	summary := r.Summary()
+
+	keys := make([]string, 0, len(summary))
+	for key := range summary {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		s := summary[key]
+		fmt.Fprintf(&b, "\n%-36s %5d calls, status %v, %d errors, p50 %s, p95 %s, p99 %s",
+			key, s.Calls, s.StatusCodes, s.Errors, s.Latencies.P50, s.Latencies.P95, s.Latencies.P99)
+	}
+	return b.String()
+}
+
+// endpoint replaces the function name in the API paths that contain one.
+func endpoint(method, path string) string {
+	for _, prefix := range []string{"/system/function/", "/system/scale-function/", "/function/"} {
+		if i := strings.Index(path, prefix); i >= 0 {
+			path = path[:i] + prefix + "{name}"
+			break
+		}
+	}
+	return method + " " + path
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+	types "github.com/openfaas/faas-provider/types"
+	"github.com/rakyll/hey/requester"
+)
+
+// autoscalingHold is how long the target replica count must be kept while
+// the load continues
+const autoscalingHold = 30 * time.Second
+
+// autoscalingScenario deploys a function with one scaling policy and drives
+// load shaped for it, so the expected replica count follows from the policy.
+type autoscalingScenario struct {
+	policy   string
+	function string
+	image    func(t *testing.T) string
+	env      map[string]string
+	labels   map[string]string
+	min      uint64
+	max      uint64
+	// target is the replica count the policy should settle on under the load
+	target uint64
+
+	// concurrency and qps shape the load, qps is per worker and unlimited
+	// when 0
+	concurrency int
+	qps         float64
+}
+
+func Test_AutoscalingPolicies(t *testing.T) {
+	if !config.EnableScaling {
+		skipf(t, "autoscaling is not supported for %s", config.ProviderName)
+	}
+	requireGateway(t, "autoscaling")
+
+	hash := func(t *testing.T) string { return fixtureImage(t, "hash") }
+
+	scenarios := []autoscalingScenario{
+		{
+			// every firing alert adds factor% of max replicas
+			policy:      scalePolicyFactor,
+			function:    "test-autoscale-factor",
+			image:       hash,
+			labels:      map[string]string{"com.openfaas.scale.factor": "50"},
+			min:         1,
+			max:         4,
+			target:      4,
+			concurrency: 4,
+			qps:         5,
+		},
+		{
+			// 3 workers at 5 rps against a target of 5 rps per replica
+			policy:      scalePolicyRPS,
+			function:    "test-autoscale-rps",
+			image:       hash,
+			labels:      map[string]string{"com.openfaas.scale.target": "5"},
+			min:         1,
+			max:         5,
+			target:      3,
+			concurrency: 3,
+			qps:         5,
+		},
+		{
+			// 6 requests of 1s in flight against a target of 2 per replica
+			policy:      scalePolicyCapacity,
+			function:    "test-autoscale-capacity",
+			image:       func(t *testing.T) string { return fixtureImage(t, "sleep") },
+			env:         map[string]string{"sleep_duration": "1s"},
+			labels:      map[string]string{"com.openfaas.scale.target": "2"},
+			min:         1,
+			max:         5,
+			target:      3,
+			concurrency: 6,
+		},
+		{
+			// 3 busy CPUs against a target of 1000 millicores per replica
+			policy:      scalePolicyCPU,
+			function:    "test-autoscale-cpu",
+			image:       func(t *testing.T) string { return fixtureImage(t, "cpu") },
+			env:         map[string]string{"cpu_duration": "200ms"},
+			labels:      map[string]string{"com.openfaas.scale.target": "1000"},
+			min:         1,
+			max:         5,
+			target:      3,
+			concurrency: 3,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.policy, func(t *testing.T) {
+			if !config.Profile.Autoscaling.Supports(s.policy) {
+				skipf(t, "the %s autoscaling policy is not supported by the %s profile", s.policy, config.ProviderName)
+			}
+			testAutoscalingScenario(t, s)
+		})
+	}
+}
+
+func testAutoscalingScenario(t *testing.T, s autoscalingScenario) {
+	labels := map[string]string{
+		"com.openfaas.scale.min": fmt.Sprintf("%d", s.min),
+		"com.openfaas.scale.max": fmt.Sprintf("%d", s.max),
+	}
+	if s.policy != scalePolicyFactor {
+		labels["com.openfaas.scale.type"] = s.policy
+	}
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        s.image(t),
+		FunctionName: s.function,
+		Network:      "func_functions",
+		EnvVars:      s.env,
+		Labels:       labels,
+		Namespace:    config.DefaultNamespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err := waitForFunctionStatus(time.Minute, s.function, config.DefaultNamespace, minAvailableReplicaCount(s.min))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", s.function, err)
+	}
+
+	functionLoad := startLoad(t, functionRequest, &requester.Work{
+		N:                 1000000,
+		Timeout:           10,
+		C:                 s.concurrency,
+		QPS:               s.qps,
+		DisableKeepAlives: true,
+	})
+
+	start := time.Now()
+	err = waitForFunctionStatus(config.ScaleTimeout, s.function, config.DefaultNamespace, minReplicaCount(s.target))
+	if err != nil {
+		result := functionLoad.Stop(t)
+		fnc := get(t, s.function, config.DefaultNamespace)
+		t.Logf("function load %s", result)
+		t.Fatalf("never reached %d replicas within %s, got %d", s.target, config.ScaleTimeout, fnc.Replicas)
+	}
+	t.Logf("%d replicas after %s", s.target, time.Since(start).Round(time.Second))
+
+	var status types.FunctionStatus
+	held := statusNeverReached(t, autoscalingHold, s.function, config.DefaultNamespace, func(fnc types.FunctionStatus) bool {
+		status = fnc
+		return fnc.Replicas < s.target || fnc.Replicas > s.max
+	})
+	result := functionLoad.Stop(t)
+	if !held {
+		t.Logf("function load %s", result)
+		t.Fatalf("replicas changed to %d while the load continued, wanted between %d and %d for %s",
+			status.Replicas, s.target, s.max, autoscalingHold)
+	}
+
+	stopped := time.Now()
+	t.Run("scale down window", func(t *testing.T) {
+		window := config.Profile.Autoscaling.ScaleDownWindow.Duration
+		if window <= 0 {
+			skipf(t, "the profile does not declare autoscaling.scaleDownWindow")
+		}
+		if !statusNeverReached(t, window, s.function, config.DefaultNamespace, maxReplicaCount(s.target-1)) {
+			t.Fatalf("scaled down %s after the load stopped, before the %s stabilization window",
+				time.Since(stopped).Round(time.Second), window)
+		}
+	})
+
+	err = waitForFunctionStatus(config.ScaleTimeout, s.function, config.DefaultNamespace, maxReplicaCount(s.min))
+	if err != nil {
+		fnc := get(t, s.function, config.DefaultNamespace)
+		t.Fatalf("never scaled down to %d replicas within %s of the stabilization window, got %d",
+			s.min, config.ScaleTimeout, fnc.Replicas)
+	}
+	t.Logf("scaled down to %d replicas %s after the load stopped", s.min, time.Since(stopped).Round(time.Second))
+}
@@ -0,0 +1,297 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/openfaas/certifier/internal/stats"
+	sdk "github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-provider/logs"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+// The benchmarks time the management API of the provider, run them with
+//
+//	go test ./tests -run '^$' -bench . -benchtime 20x -count 6 | tee new.txt
+//	benchstat old.txt new.txt
+//
+// Next to ns/op every benchmark reports the p50, p95 and p99 latency of the
+// timed call.
+
+func BenchmarkDeployFunction(b *testing.B) {
+	// the benchmark runs several times with a growing b.N, new names avoid
+	// racing the deletion of the previous run
+	run := RandString(6)
+	var specs []*sdk.DeployFunctionSpec
+	defer func() { deleteAll(b, specs) }()
+
+	benchOp(b, func(i int) {
+		spec := benchSpec(b, fmt.Sprintf("bench-deploy-%s-%d", run, i))
+		specs = append(specs, spec)
+	}, func(i int) {
+		if status := tryDeploy(specs[i]); status != http.StatusOK && status != http.StatusAccepted {
+			b.Fatalf("deploy %s got %d", specs[i].FunctionName, status)
+		}
+	}, nil)
+}
+
+func BenchmarkGetFunctionInfo(b *testing.B) {
+	spec := benchSpec(b, "bench-describe-"+RandString(6))
+	benchDeploy(b, spec)
+	defer deleteAll(b, []*sdk.DeployFunctionSpec{spec})
+
+	benchOp(b, nil, func(i int) {
+		if _, err := config.Client.GetFunctionInfo(context.Background(), spec.FunctionName, spec.Namespace); err != nil {
+			b.Fatalf("describe %s failed: %s", spec.FunctionName, err)
+		}
+	}, nil)
+}
+
+func BenchmarkListFunctions(b *testing.B) {
+	run := RandString(6)
+	var specs []*sdk.DeployFunctionSpec
+	defer func() { deleteAll(b, specs) }()
+
+	for _, count := range []int{10, 100, 1000} {
+		var added []*sdk.DeployFunctionSpec
+		for i := len(specs); i < count; i++ {
+			added = append(added, benchSpec(b, fmt.Sprintf("bench-list-%s-%04d", run, i)))
+		}
+		specs = append(specs, added...)
+		populate(b, added)
+
+		b.Run(fmt.Sprintf("functions=%d", count), func(b *testing.B) {
+			benchOp(b, nil, func(i int) {
+				functions, err := config.Client.ListFunctions(context.Background(), config.DefaultNamespace)
+				if err != nil {
+					b.Fatalf("list failed: %s", err)
+				}
+				if len(functions) < count {
+					b.Fatalf("got %d functions, wanted at least %d", len(functions), count)
+				}
+			}, nil)
+		})
+	}
+}
+
+func BenchmarkScaleFunction(b *testing.B) {
+	if !config.EnableScaling {
+		b.Skipf("scaling is not supported for %s", config.ProviderName)
+	}
+
+	spec := benchSpec(b, "bench-scale-"+RandString(6))
+	benchDeploy(b, spec)
+	defer deleteAll(b, []*sdk.DeployFunctionSpec{spec})
+
+	benchOp(b, nil, func(i int) {
+		replicas := uint64(i % 2)
+		if err := config.Client.ScaleFunction(context.Background(), spec.FunctionName, spec.Namespace, replicas); err != nil {
+			b.Fatalf("scale %s to %d failed: %s", spec.FunctionName, replicas, err)
+		}
+	}, nil)
+}
+
+func BenchmarkSecretCRUD(b *testing.B) {
+	ctx := context.Background()
+	run := RandString(6)
+	secret := func(i int) types.Secret {
+		return types.Secret{
+			Name:      fmt.Sprintf("bench-secret-%s-%d", run, i),
+			Value:     "bench-secret-value",
+			Namespace: config.DefaultNamespace,
+		}
+	}
+
+	create := func(i int) {
+		if status, out := config.Client.CreateSecret(ctx, secret(i)); status != http.StatusOK && status != http.StatusAccepted && status != http.StatusCreated {
+			b.Fatalf("create secret got %d: %s", status, out)
+		}
+	}
+	remove := func(i int) {
+		if err := config.Client.RemoveSecret(ctx, secret(i)); err != nil {
+			b.Fatalf("remove secret failed: %s", err)
+		}
+	}
+
+	b.Run("create", func(b *testing.B) {
+		benchOp(b, nil, create, remove)
+	})
+
+	b.Run("update", func(b *testing.B) {
+		if !config.SecretUpdate {
+			b.Skipf("secret update is not supported for %s", config.ProviderName)
+		}
+
+		create(0)
+		defer remove(0)
+
+		benchOp(b, nil, func(i int) {
+			s := secret(0)
+			s.Value = fmt.Sprintf("bench-secret-value-%d", i)
+			if status, out := config.Client.UpdateSecret(ctx, s); status != http.StatusOK && status != http.StatusAccepted {
+				b.Fatalf("update secret got %d: %s", status, out)
+			}
+		}, nil)
+	})
+
+	b.Run("delete", func(b *testing.B) {
+		benchOp(b, create, remove, nil)
+	})
+}
+
+func BenchmarkGetLogs(b *testing.B) {
+	spec := benchSpec(b, "bench-logs-"+RandString(6))
+	spec.Image = fixtureImage(b, "logger")
+	benchDeploy(b, spec)
+	defer deleteAll(b, []*sdk.DeployFunctionSpec{spec})
+
+	if err := waitForInvoke(b, spec, time.Minute); err != nil {
+		b.Fatalf("invoke %s failed: %s", spec.FunctionName, err)
+	}
+
+	benchOp(b, nil, func(i int) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		messages, err := config.Client.GetLogs(ctx, logs.Request{Name: spec.FunctionName, Namespace: spec.Namespace, Tail: 10})
+		if err != nil {
+			b.Fatalf("logs of %s failed: %s", spec.FunctionName, err)
+		}
+		for range messages {
+		}
+	}, nil)
+}
+
+func BenchmarkDeployToFirstInvoke(b *testing.B) {
+	run := RandString(6)
+	var specs []*sdk.DeployFunctionSpec
+
+	benchOp(b, func(i int) {
+		specs = append(specs, benchSpec(b, fmt.Sprintf("bench-first-invoke-%s-%d", run, i)))
+	}, func(i int) {
+		if status := tryDeploy(specs[i]); status != http.StatusOK && status != http.StatusAccepted {
+			b.Fatalf("deploy %s got %d", specs[i].FunctionName, status)
+		}
+		if err := waitForInvoke(b, specs[i], 2*time.Minute); err != nil {
+			b.Fatalf("invoke %s failed: %s", specs[i].FunctionName, err)
+		}
+	}, func(i int) {
+		_ = config.Client.DeleteFunction(context.Background(), specs[i].FunctionName, specs[i].Namespace)
+	})
+}
+
+// benchOp runs op b.N times and reports its latency percentiles, setup and
+// teardown run before and after every op without the timer.
+func benchOp(b *testing.B, setup, op, teardown func(i int)) {
+	b.Helper()
+
+	durations := make([]time.Duration, 0, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if setup != nil {
+			b.StopTimer()
+			setup(i)
+			b.StartTimer()
+		}
+
+		start := time.Now()
+		op(i)
+		durations = append(durations, time.Since(start))
+
+		if teardown != nil {
+			b.StopTimer()
+			teardown(i)
+			b.StartTimer()
+		}
+	}
+	b.StopTimer()
+
+	s := stats.Summarize(durations)
+	b.ReportMetric(float64(s.P50), "p50-ns/op")
+	b.ReportMetric(float64(s.P95), "p95-ns/op")
+	b.ReportMetric(float64(s.P99), "p99-ns/op")
+}
+
+func benchSpec(b testing.TB, name string) *sdk.DeployFunctionSpec {
+	return &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(b, "hash"),
+		FunctionName: name,
+		Network:      "func_functions",
+		Namespace:    config.DefaultNamespace,
+	}
+}
+
+func benchDeploy(b *testing.B, spec *sdk.DeployFunctionSpec) {
+	b.Helper()
+
+	if status := tryDeploy(spec); status != http.StatusOK && status != http.StatusAccepted {
+		b.Fatalf("deploy %s got %d", spec.FunctionName, status)
+	}
+
+	if err := waitForFunctionStatus(time.Minute, spec.FunctionName, spec.Namespace, minAvailableReplicaCount(1)); err != nil {
+		b.Fatalf("Function %q failed to start: %s", spec.FunctionName, err)
+	}
+}
+
+// populate deploys the functions concurrently and scales them to zero when
+// possible, so that hundreds of them fit into the cluster.
+func populate(b *testing.B, specs []*sdk.DeployFunctionSpec) {
+	b.Helper()
+
+	stdout := os.Stdout
+	os.Stdout = devnull
+	defer func() { os.Stdout = stdout }()
+
+	stressPhase(b, "deploy", specs, func(spec *sdk.DeployFunctionSpec) error {
+		if status := config.Client.DeployFunction(context.Background(), spec); status != http.StatusOK && status != http.StatusAccepted {
+			return fmt.Errorf("deploy %s got %d", spec.FunctionName, status)
+		}
+		return nil
+	})
+
+	if config.EnableScaling {
+		stressPhase(b, "scale", specs, func(spec *sdk.DeployFunctionSpec) error {
+			return config.Client.ScaleFunction(context.Background(), spec.FunctionName, spec.Namespace, 0)
+		})
+	}
+}
+
+// deleteAll removes the functions concurrently, ignoring errors.
+func deleteAll(b *testing.B, specs []*sdk.DeployFunctionSpec) {
+	b.Helper()
+
+	stressPhase(b, "delete", specs, func(spec *sdk.DeployFunctionSpec) error {
+		_ = config.Client.DeleteFunction(context.Background(), spec.FunctionName, spec.Namespace)
+		return nil
+	})
+}
+
+// waitForInvoke invokes the function until it returns 200 OK.
+func waitForInvoke(b *testing.B, spec *sdk.DeployFunctionSpec, timeout time.Duration) error {
+	b.Helper()
+
+	uri := resourceURL(b, path.Join("function", fmt.Sprintf("%s.%s", spec.FunctionName, spec.Namespace)), "")
+
+	client := http.Client{Timeout: 10 * time.Second}
+	deadline := time.Now().Add(timeout)
+	for {
+		res, err := client.Post(uri, "text/plain", nil)
+		if err == nil {
+			res.Body.Close()
+			if res.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("got %d", res.StatusCode)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not invokable after %s: %w", timeout, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
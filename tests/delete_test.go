@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+func Test_DeleteFunction(t *testing.T) {
+	functionName := "test-delete-function"
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "hash"),
+		FunctionName: functionName,
+		Network:      "func_functions",
+		Namespace:    config.DefaultNamespace,
+	}
+
+	deployAndInvoke(t, functionRequest)
+
+	deleted := time.Now()
+	deleteFunction(t, functionRequest)
+
+	var pending []string
+	ctx, cancel := context.WithTimeout(context.Background(), config.DeleteTimeout)
+	defer cancel()
+	for ctx.Err() == nil {
+		pending = deletePending(t, functionRequest)
+		if len(pending) == 0 {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+
+	if len(pending) > 0 {
+		t.Fatalf("%s after delete: %s", config.DeleteTimeout, strings.Join(pending, ", "))
+	}
+	t.Logf("delete converged after %s", time.Since(deleted).Round(time.Second))
+
+	statusCode, body := deleteRequest(t, functionName, functionRequest.Namespace)
+	if statusCode != http.StatusNotFound {
+		t.Fatalf("second delete got %d, wanted %d: %s", statusCode, http.StatusNotFound, body)
+	}
+}
+
+func Test_RedeployAfterDelete(t *testing.T) {
+	functionName := "test-redeploy-function"
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "hash"),
+		FunctionName: functionName,
+		Network:      "func_functions",
+		EnvVars:      map[string]string{"revision": "1"},
+		Namespace:    config.DefaultNamespace,
+	}
+
+	deployAndInvoke(t, functionRequest)
+	deleteFunction(t, functionRequest)
+
+	// right away, without waiting for the delete to converge
+	functionRequest.EnvVars = map[string]string{"revision": "2"}
+	deployAndInvoke(t, functionRequest)
+	defer deleteFunction(t, functionRequest)
+
+	// the earlier delete must not remove the new deployment once it
+	// completes
+	kept := statusNeverReached(t, config.DeleteTimeout, functionName, functionRequest.Namespace, func(fnc types.FunctionStatus) bool {
+		return fnc.EnvVars["revision"] != "2"
+	})
+	if !kept {
+		t.Fatalf("redeployed function was replaced or removed")
+	}
+
+	fnc := get(t, functionName, functionRequest.Namespace)
+	if fnc.EnvVars["revision"] != "2" {
+		t.Fatalf("got env %v, wanted revision 2", fnc.EnvVars)
+	}
+	_ = invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+}
+
+func deployAndInvoke(t *testing.T, functionRequest *sdk.DeployFunctionSpec) {
+	t.Helper()
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+
+	err := waitForFunctionStatus(time.Minute, functionRequest.FunctionName, functionRequest.Namespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", functionRequest.FunctionName, err)
+	}
+
+	_ = invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+}
+
+// deletePending lists the ways the deleted function is still visible.
+func deletePending(t *testing.T, function *sdk.DeployFunctionSpec) []string {
+	t.Helper()
+
+	var pending []string
+
+	uri := resourceURL(t, path.Join("system", "function", function.FunctionName), "namespace="+function.Namespace)
+	_, res := request(t, uri, http.MethodGet, config.Auth, nil)
+	if res.StatusCode != http.StatusNotFound {
+		pending = append(pending, fmt.Sprintf("describe got %d, wanted %d", res.StatusCode, http.StatusNotFound))
+	}
+
+	functions, err := config.Client.ListFunctions(context.Background(), function.Namespace)
+	if err != nil {
+		t.Fatalf("list functions failed: %s", err)
+	}
+	for _, fn := range functions {
+		if fn.Name == function.FunctionName {
+			pending = append(pending, "still listed")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	uri = resourceURL(t, path.Join("function", fmt.Sprintf("%s.%s", function.FunctionName, function.Namespace)), "")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, nil)
+	if err != nil {
+		t.Fatalf("error with request %s ", err)
+	}
+
+	res, err = http.DefaultClient.Do(req)
+	switch {
+	case err != nil:
+		pending = append(pending, fmt.Sprintf("invoke failed instead of returning %d: %s", http.StatusNotFound, err))
+	case res.StatusCode != http.StatusNotFound:
+		res.Body.Close()
+		pending = append(pending, fmt.Sprintf("invoke got %d, wanted %d", res.StatusCode, http.StatusNotFound))
+	default:
+		res.Body.Close()
+	}
+
+	return pending
+}
+
+// deleteRequest calls DELETE /system/functions directly so the status code
+// can be asserted.
+func deleteRequest(t *testing.T, name, namespace string) (int, string) {
+	t.Helper()
+
+	body, err := json.Marshal(types.DeleteFunctionRequest{FunctionName: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := resourceURL(t, path.Join("system", "functions"), "namespace="+namespace)
+	out, res := request(t, uri, http.MethodDelete, config.Auth, strings.NewReader(string(body)))
+	return res.StatusCode, string(out)
+}
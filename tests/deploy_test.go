@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openfaas/certifier/internal/images"
 	types "github.com/openfaas/faas-provider/types"
 )
 
@@ -50,15 +51,15 @@ func Test_Deploy_MetaData(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	imagePath := config.RegistryPrefix + "/" + "functions/alpine:latest"
+	hashImage := fixtureImage(t, "hash")
+	echoImage := fixtureImage(t, "echo")
 
 	cases := []FunctionTestCase{
 		{
 			name: "Deploy without any extra metadata",
 			function: types.FunctionDeployment{
-				Image:       imagePath,
+				Image:       hashImage,
 				Service:     "stronghash",
-				EnvProcess:  "sha512sum",
 				Annotations: &map[string]string{},
 				Labels:      &map[string]string{},
 				Namespace:   config.DefaultNamespace,
@@ -67,9 +68,8 @@ func Test_Deploy_MetaData(t *testing.T) {
 		{
 			name: "Deploy with labels",
 			function: types.FunctionDeployment{
-				Image:       imagePath,
+				Image:       echoImage,
 				Service:     "env-test-labels",
-				EnvProcess:  "env",
 				Annotations: &map[string]string{},
 				Labels: &map[string]string{
 					"upstream_uri": "example.com",
@@ -81,9 +81,8 @@ func Test_Deploy_MetaData(t *testing.T) {
 		{
 			name: "Deploy with annotations",
 			function: types.FunctionDeployment{
-				Image:      imagePath,
-				Service:    "env-test-annotations",
-				EnvProcess: "env",
+				Image:   echoImage,
+				Service: "env-test-annotations",
 				Annotations: &map[string]string{
 					"important-date": "Fri Aug 10 08:21:00 BST 2018",
 					"some-json":      someAnnotationJson,
@@ -95,9 +94,8 @@ func Test_Deploy_MetaData(t *testing.T) {
 		{
 			name: "Deploy with memory limit",
 			function: types.FunctionDeployment{
-				Image:       imagePath,
+				Image:       echoImage,
 				Service:     "memory-limit",
-				EnvProcess:  "env",
 				Annotations: &map[string]string{},
 				Labels:      &map[string]string{},
 				Namespace:   config.DefaultNamespace,
@@ -168,8 +166,8 @@ func Test_Deploy_MetaData(t *testing.T) {
 						namespace, expectedF.Service, err)
 				}
 
-				if info.Image != expectedF.Image {
-					t.Fatalf("want image %s, but got %s", expectedF.Image, info.Image)
+				if err := images.Compare(expectedF.Image, info.Image, config.Profile.ImageComparison); err != nil {
+					t.Fatal(err)
 				}
 
 				delete(expected, actualF.Name)
@@ -215,8 +213,8 @@ func compareDeployAndStatus(deploy types.FunctionDeployment, status types.Functi
 	if deploy.Service != status.Name {
 		return fmt.Errorf("got %v, expected name %s", status.Name, deploy.Service)
 	}
-	if deploy.Image != status.Image {
-		return fmt.Errorf("got %v, expected image %s", status.Image, deploy.Image)
+	if err := images.Compare(deploy.Image, status.Image, config.Profile.ImageComparison); err != nil {
+		return err
 	}
 	if deploy.Namespace != status.Namespace {
 		return fmt.Errorf("got %v, expected Namespace %s", status.Namespace, deploy.Namespace)
@@ -307,6 +305,31 @@ func strMapEqual(mapName string, got map[string]string, wanted map[string]string
 	return nil
 }
 
+// strMapDiff compares the reported map with the submitted one. mismatches
+// lists the submitted keys that are missing or have another value, added the
+// keys that the provider added on its own.
+func strMapDiff(got map[string]string, wanted map[string]string) (mismatches []string, added []string) {
+	for k, v := range wanted {
+		value, ok := got[k]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("%s is missing", k))
+		case value != v:
+			mismatches = append(mismatches, fmt.Sprintf("%s is %.80q, wanted %.80q", k, value, v))
+		}
+	}
+
+	for k := range got {
+		if _, ok := wanted[k]; !ok {
+			added = append(added, k)
+		}
+	}
+
+	sort.Strings(mismatches)
+	sort.Strings(added)
+	return mismatches, added
+}
+
 func strSliceEqual(got, wanted []string) error {
 	if len(got) != len(wanted) {
 		return fmt.Errorf("incorrect number of entries")
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	echo "github.com/openfaas/certifier/functions/echo"
+	"github.com/openfaas/certifier/internal/images"
+	sdk "github.com/openfaas/faas-cli/proxy"
+)
+
+func Test_EnvEdgeCases(t *testing.T) {
+	envVars := map[string]string{
+		"with_equals":   "key=value=more",
+		"with_quotes":   `single ' double " back ` + "` end",
+		"with_newlines": "line one\nline two\r\n\n",
+		"with_unicode":  "héllo wörld ✓ 日本語 🚀",
+		"with_spaces":   "  leading and trailing  ",
+		"empty":         "",
+	}
+
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "echo"),
+		FunctionName: "test-env-edge-cases",
+		Network:      "func_functions",
+		// the fixture ignores fprocess, the value checks that the provider
+		// passes it through without splitting or unquoting it
+		FProcess:  `sh -c "echo 'quoted  argument' | tr a-z A-Z"`,
+		EnvVars:   envVars,
+		Namespace: config.DefaultNamespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err := waitForFunctionStatus(time.Minute, functionRequest.FunctionName, functionRequest.Namespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", functionRequest.FunctionName, err)
+	}
+
+	var res echo.Response
+	out := invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+	if err := json.Unmarshal(out, &res); err != nil {
+		t.Fatalf("invalid echo response %.200q: %s", out, err)
+	}
+
+	for name, want := range envVars {
+		name, want := name, want
+		t.Run("process sees "+name, func(t *testing.T) {
+			got, ok := res.Env[name]
+			if !ok {
+				t.Fatalf("%s is not set", name)
+			}
+			if got != want {
+				t.Fatalf("got %q, wanted %q", got, want)
+			}
+		})
+	}
+
+	t.Run("process sees fprocess", func(t *testing.T) {
+		if got := res.Env["fprocess"]; got != functionRequest.FProcess {
+			t.Fatalf("got %q, wanted %q", got, functionRequest.FProcess)
+		}
+	})
+
+	t.Run("GetFunctionInfo returns the values as submitted", func(t *testing.T) {
+		function := get(t, functionRequest.FunctionName, functionRequest.Namespace)
+		if function.EnvProcess != functionRequest.FProcess {
+			t.Errorf("got EnvProcess %q, wanted %q", function.EnvProcess, functionRequest.FProcess)
+		}
+		if !reflect.DeepEqual(function.EnvVars, envVars) {
+			t.Errorf("got EnvVars %q, wanted %q", function.EnvVars, envVars)
+		}
+	})
+}
+
+func Test_EnvCanNotOverrideRequestVariables(t *testing.T) {
+	envVars := map[string]string{
+		"Http_Method": "OVERRIDE",
+		"Http_Path":   "/override",
+		"Http_Query":  "override=1",
+	}
+
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        resolveImage(images.Alpine),
+		FunctionName: "test-env-http-override",
+		Network:      "func_functions",
+		FProcess:     "env",
+		EnvVars:      envVars,
+		Namespace:    config.DefaultNamespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err := waitForFunctionStatus(time.Minute, functionRequest.FunctionName, functionRequest.Namespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", functionRequest.FunctionName, err)
+	}
+
+	_ = invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+
+	// the classic watchdog sets Http_Path to the path below the function
+	uri := resourceURL(t, path.Join("function", functionRequest.FunctionName+"."+functionRequest.Namespace, "sub", "path"), "testing=1")
+	body, res := request(t, uri, http.MethodPost, nil, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("invoke of the sub path got %d, wanted %d: %s", res.StatusCode, http.StatusOK, body)
+	}
+
+	out := string(body)
+	env := map[string][]string{}
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = append(env[parts[0]], parts[1])
+		}
+	}
+
+	// invoke sends a POST
+	want := map[string]string{
+		"Http_Method": http.MethodPost,
+		"Http_Path":   "/sub/path",
+		"Http_Query":  "testing=1",
+	}
+	for name, value := range want {
+		if got := env[name]; len(got) != 1 || got[0] != value {
+			t.Errorf("process sees %s=%q, wanted %q from the request", name, got, value)
+		}
+	}
+
+	function := get(t, functionRequest.FunctionName, functionRequest.Namespace)
+	if !reflect.DeepEqual(function.EnvVars, envVars) {
+		t.Errorf("got EnvVars %q, wanted %q", function.EnvVars, envVars)
+	}
+}
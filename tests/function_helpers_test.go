@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -16,6 +17,19 @@ var devnull = os.NewFile(0, os.DevNull)
 
 func deploy(t *testing.T, createRequest *sdk.DeployFunctionSpec) int {
 	t.Helper()
+
+	statusCode := tryDeploy(createRequest)
+	if statusCode >= 400 {
+		t.Fatalf("unable to deploy function (%s.%s): %d",
+			createRequest.FunctionName, createRequest.Namespace, statusCode)
+	}
+
+	return statusCode
+}
+
+// tryDeploy deploys the function and returns the status code without failing
+// the test, use it when a deployment may legitimately be rejected.
+func tryDeploy(createRequest *sdk.DeployFunctionSpec) int {
 	var stdout *os.File
 
 	// suppress the sdk fmt.Println, this hides statements like this that provide no
@@ -27,13 +41,7 @@ func deploy(t *testing.T, createRequest *sdk.DeployFunctionSpec) int {
 		os.Stdout = stdout
 	}()
 
-	statusCode := config.Client.DeployFunction(context.Background(), createRequest)
-	if statusCode >= 400 {
-		t.Fatalf("unable to deploy function (%s.%s): %d",
-			createRequest.FunctionName, createRequest.Namespace, statusCode)
-	}
-
-	return statusCode
+	return config.Client.DeployFunction(context.Background(), createRequest)
 }
 
 func list(t *testing.T, expectedStatusCode int, namespace string) []types.FunctionStatus {
@@ -87,6 +95,35 @@ func copyNamespacesTest(cases []FunctionTestCase) []FunctionTestCase {
 	return cases
 }
 
+// resolveImage returns the reference to deploy for one of the images listed
+// by `certifier images list`, applying the registry prefix, overrides and
+// pinned digests. Every image deployed by the tests must go through here.
+func resolveImage(name string) string {
+	return config.Images.Resolve(name)
+}
+
+// skipf skips the test and records the reason in the report, so that a
+// certified run lists the checks it did not make.
+func skipf(t testing.TB, format string, args ...interface{}) {
+	t.Helper()
+
+	reason := fmt.Sprintf(format, args...)
+	report.Skip(t.Name(), reason)
+	t.Skip(reason)
+}
+
+// fixtureImage returns the resolved image of a fixture function from
+// functions/stack.yml.
+func fixtureImage(t testing.TB, name string) string {
+	t.Helper()
+	image, ok := config.Fixtures[name]
+	if !ok {
+		t.Fatalf("unknown fixture function %q in %s", name, config.FixturesStack)
+	}
+
+	return resolveImage(image)
+}
+
 func createDeploymentSpec(test FunctionTestCase) *sdk.DeployFunctionSpec {
 	functionRequest := &sdk.DeployFunctionSpec{
 		Image:        test.function.Image,
@@ -117,6 +154,10 @@ func createDeploymentSpec(test FunctionTestCase) *sdk.DeployFunctionSpec {
 	return functionRequest
 }
 
+// errStatusTimeout is returned by waitForFunctionStatus when the condition did
+// not become true in time, any other error comes from the API.
+var errStatusTimeout = errors.New("timed out waiting for the function status")
+
 // waitForFunctionStatus polls the function status endpoint until the test function returns true _or_ the specified timeout.
 func waitForFunctionStatus(timeout time.Duration, name, namespace string, test func(types.FunctionStatus) bool) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -125,6 +166,10 @@ func waitForFunctionStatus(timeout time.Duration, name, namespace string, test f
 	for ctx.Err() == nil {
 		function, err := config.Client.GetFunctionInfo(ctx, name, namespace)
 		if err != nil {
+			// the request was cut short by the timeout
+			if ctx.Err() != nil {
+				break
+			}
 			return err
 		}
 
@@ -135,7 +180,25 @@ func waitForFunctionStatus(timeout time.Duration, name, namespace string, test f
 		time.Sleep(time.Second)
 	}
 
-	return ctx.Err()
+	return fmt.Errorf("%w after %s", errStatusTimeout, timeout)
+}
+
+// statusNeverReached is waitForFunctionStatus for conditions that must not
+// become true. It returns true when the timeout passed without the condition
+// holding and fails the test when the function status can not be read.
+func statusNeverReached(t *testing.T, timeout time.Duration, name, namespace string, test func(types.FunctionStatus) bool) bool {
+	t.Helper()
+
+	err := waitForFunctionStatus(timeout, name, namespace, test)
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, errStatusTimeout):
+		return true
+	}
+
+	t.Fatalf("unable to get the status of %s.%s: %s", name, namespace, err)
+	return false
 }
 
 func maxReplicaCount(count uint64) func(types.FunctionStatus) bool {
@@ -155,3 +218,9 @@ func minAvailableReplicaCount(count uint64) func(types.FunctionStatus) bool {
 		return function.AvailableReplicas >= count
 	}
 }
+
+func availableReplicaCount(count uint64) func(types.FunctionStatus) bool {
+	return func(function types.FunctionStatus) bool {
+		return function.AvailableReplicas == count
+	}
+}
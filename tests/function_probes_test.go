@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+)
+
+// readyAfter is how long the probes fixture fails its readiness endpoint
+const readyAfter = 30 * time.Second
+
+// probesState is the response of the probes fixture.
+type probesState struct {
+	Hostname string `json:"hostname"`
+	Ready    bool   `json:"ready"`
+	Healthy  bool   `json:"healthy"`
+}
+
+func Test_HealthAndReadinessAnnotations(t *testing.T) {
+	if !config.Profile.HealthAnnotations {
+		skipf(t, "health and readiness annotations are not supported by the %s profile", config.ProviderName)
+	}
+	if !config.EnableScaling {
+		skipf(t, "taking a replica out of rotation needs scale.min=2, which is not supported for %s", config.ProviderName)
+	}
+
+	functionName := "test-health-readiness"
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "probes"),
+		FunctionName: functionName,
+		Network:      "func_functions",
+		EnvVars:      map[string]string{"ready_after": readyAfter.String()},
+		Labels:       map[string]string{"com.openfaas.scale.min": "2"},
+		Annotations: map[string]string{
+			"com.openfaas.ready.http.path":          "/ready",
+			"com.openfaas.ready.http.initialDelay":  "2s",
+			"com.openfaas.health.http.path":         "/healthz",
+			"com.openfaas.health.http.initialDelay": "2s",
+		},
+		Namespace: config.DefaultNamespace,
+	}
+
+	deployed := time.Now()
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	t.Run("not available until ready", func(t *testing.T) {
+		// leave a margin, the replicas start some time after the deployment
+		for time.Since(deployed) < readyAfter-5*time.Second {
+			fnc := get(t, functionName, config.DefaultNamespace)
+			if fnc.AvailableReplicas != 0 {
+				t.Fatalf("got %d available replicas %s after deploy, wanted 0 until the readiness path passes after %s",
+					fnc.AvailableReplicas, time.Since(deployed).Round(time.Second), readyAfter)
+			}
+
+			state, statusCode, err := invokeProbes(t, functionRequest, "", 2*time.Second)
+			if err == nil && statusCode == http.StatusOK && !state.Ready {
+				t.Fatalf("invocation was routed to %s before its readiness path passed", state.Hostname)
+			}
+
+			time.Sleep(time.Second)
+		}
+
+		err := waitForFunctionStatus(config.ScaleTimeout, functionName, config.DefaultNamespace, minAvailableReplicaCount(2))
+		if err != nil {
+			t.Fatalf("replicas never became available once ready: %s", err)
+		}
+		t.Logf("2 available replicas %s after deploy", time.Since(deployed).Round(time.Second))
+
+		state, statusCode, err := invokeProbes(t, functionRequest, "", 10*time.Second)
+		if err != nil || statusCode != http.StatusOK {
+			t.Fatalf("invoke after ready got %d: %v", statusCode, err)
+		}
+		if !state.Ready {
+			t.Fatalf("invocation was routed to %s before its readiness path passed", state.Hostname)
+		}
+	})
+
+	t.Run("unhealthy replica is taken out of rotation", func(t *testing.T) {
+		state, statusCode, err := invokeProbes(t, functionRequest, "unhealthy", 10*time.Second)
+		if err != nil || statusCode != http.StatusOK {
+			t.Fatalf("marking a replica unhealthy got %d: %v", statusCode, err)
+		}
+		marked := time.Now()
+		t.Logf("marked %s unhealthy", state.Hostname)
+
+		// the provider may need a few failed probes to notice, after that
+		// every invocation must be served by a healthy replica
+		healthyInARow := 0
+		for healthyInARow < 20 {
+			if time.Since(marked) > config.ScaleTimeout {
+				t.Fatalf("unhealthy replica %s still served invocations %s after it was marked", state.Hostname, config.ScaleTimeout)
+			}
+
+			served, statusCode, err := invokeProbes(t, functionRequest, "", 10*time.Second)
+			switch {
+			case err == nil && statusCode == http.StatusOK && served.Healthy && served.Ready:
+				healthyInARow++
+			default:
+				healthyInARow = 0
+			}
+
+			time.Sleep(500 * time.Millisecond)
+		}
+		t.Logf("unhealthy replica out of rotation after %s", time.Since(marked).Round(time.Second))
+
+		for i := 0; i < 10; i++ {
+			served, statusCode, err := invokeProbes(t, functionRequest, "", 10*time.Second)
+			if err != nil || statusCode != http.StatusOK {
+				t.Fatalf("invoke got %d: %v", statusCode, err)
+			}
+			if !served.Healthy {
+				t.Fatalf("unhealthy replica %s was routed an invocation after being taken out of rotation", served.Hostname)
+			}
+		}
+	})
+}
+
+// invokeProbes calls the probes fixture once, without retries, on the given
+// sub path.
+func invokeProbes(t *testing.T, function *sdk.DeployFunctionSpec, subPath string, timeout time.Duration) (probesState, int, error) {
+	t.Helper()
+
+	uri := resourceURL(t, path.Join("function", fmt.Sprintf("%s.%s", function.FunctionName, function.Namespace), subPath), "")
+
+	client := http.Client{Timeout: timeout}
+	res, err := client.Post(uri, "text/plain", nil)
+	if err != nil {
+		return probesState{}, 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return probesState{}, res.StatusCode, err
+	}
+
+	var state probesState
+	if res.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(body, &state); err != nil {
+			return state, res.StatusCode, fmt.Errorf("invalid probes response %q: %w", body, err)
+		}
+	}
+	return state, res.StatusCode, nil
+}
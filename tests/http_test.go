@@ -14,7 +14,7 @@ import (
 
 // resourceURL safely constructs the API url based on the `gateway_url`
 // in the ENV.
-func resourceURL(t *testing.T, reqPath, query string) string {
+func resourceURL(t testing.TB, reqPath, query string) string {
 	t.Helper()
 	uri, err := url.Parse(config.Gateway)
 	if err != nil {
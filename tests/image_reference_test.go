@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	echo "github.com/openfaas/certifier/functions/echo"
+	"github.com/openfaas/certifier/internal/images"
+	"github.com/openfaas/certifier/internal/oci"
+	"github.com/openfaas/certifier/internal/registry"
+	sdk "github.com/openfaas/faas-cli/proxy"
+)
+
+func Test_Deploy_ImageReference(t *testing.T) {
+	base := fixtureImage(t, "echo")
+	ref, err := images.ParseReference(base)
+	if err != nil {
+		t.Fatalf("invalid image %s: %s", base, err)
+	}
+
+	digest := ref.Digest
+	if digest == "" {
+		digest = fixtureDigest("echo")
+	}
+	if digest == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		client := &registry.Client{}
+		digest, err = client.Digest(ctx, ref)
+		if err != nil {
+			t.Fatalf("unable to resolve the digest of %s, pin it with -images instead: %s", base, err)
+		}
+	}
+
+	// keep the repository as it was spelled, the provider may only see the
+	// un-normalized form
+	repository := repositoryOf(base)
+	tag := ref.Tag
+	if tag == "" {
+		tag = images.DefaultTag
+	}
+
+	cases := []struct {
+		name     string
+		function string
+		image    string
+	}{
+		{
+			name:     "Deploy without a tag",
+			function: "image-without-tag",
+			image:    repository,
+		},
+		{
+			name:     "Deploy by digest",
+			function: "image-by-digest",
+			image:    repository + "@" + digest,
+		},
+		{
+			name:     "Deploy by tag and digest",
+			function: "image-by-tag-digest",
+			image:    repository + ":" + tag + "@" + digest,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			functionRequest := &sdk.DeployFunctionSpec{
+				Image:        c.image,
+				FunctionName: c.function,
+				Namespace:    config.DefaultNamespace,
+			}
+
+			deployStatus := deploy(t, functionRequest)
+			if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+				t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+			}
+			defer deleteFunction(t, functionRequest)
+
+			err := waitForFunctionStatus(time.Minute, c.function, functionRequest.Namespace, minAvailableReplicaCount(1))
+			if err != nil {
+				t.Fatalf("function %s with image %s failed to start: %s", c.function, c.image, err)
+			}
+
+			status := get(t, c.function, functionRequest.Namespace)
+			if err := images.Compare(c.image, status.Image, config.Profile.ImageComparison); err != nil {
+				t.Fatalf("describe reported the image unfaithfully: %s", err)
+			}
+
+			for _, fn := range list(t, http.StatusOK, functionRequest.Namespace) {
+				if fn.Name != c.function {
+					continue
+				}
+				if err := images.Compare(c.image, fn.Image, config.Profile.ImageComparison); err != nil {
+					t.Fatalf("list reported the image unfaithfully: %s", err)
+				}
+			}
+
+			out := invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+			var res echo.Response
+			if err := json.Unmarshal(out, &res); err != nil {
+				t.Fatalf("unable to parse the echo response %q: %s", out, err)
+			}
+			if res.Method != http.MethodPost {
+				t.Fatalf("want method: %s, got: %s", http.MethodPost, res.Method)
+			}
+		})
+	}
+}
+
+// fixtureDigest returns the manifest digest of a fixture from the fixtures
+// build output, the same digest as the loaded or pushed image because the
+// build is reproducible. It is empty when the fixtures were not built here.
+func fixtureDigest(name string) string {
+	f, err := os.Open(filepath.Join(config.FixturesDir, name+".tar"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	layout, err := oci.ReadLayout(f)
+	if err != nil || len(layout.Index.Manifests) == 0 {
+		return ""
+	}
+	return layout.Index.Manifests[0].Digest
+}
+
+// repositoryOf strips the tag and digest from an image reference.
+func repositoryOf(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
@@ -2,29 +2,31 @@ package tests
 
 import (
 	"context"
+	"fmt"
 	"testing"
-	// please only use the gateway types in this test
-	// other other tests should use the provider types
+
+	"github.com/openfaas/certifier/internal/version"
 )
 
+// knownOrchestrations are the orchestrations of the known providers, others
+// must be declared in the profile
+var knownOrchestrations = []string{"kubernetes", "containerd", "swarm", "nomad"}
+
 func Test_ProviderInfo(t *testing.T) {
-	systeminfo, err := config.Client.GetSystemInfo(context.Background())
+	provider, v, err := systemInfo(context.Background(), config.Client)
 
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if systeminfo.Provider == nil {
-		t.Fatal("provider info should be present")
-	}
-	if systeminfo.Provider.Orchestration == "" {
+	if provider.Orchestration == "" {
 		t.Fatal("provider orchestration name may not be empty")
 	}
-	if systeminfo.Provider.Name == "" {
+	if provider.Name == "" {
 		t.Fatal("provider name may not be empty")
 	}
 
-	pv := systeminfo.Provider.Version
+	pv := provider.Version
 	if pv == nil {
 		t.Fatal("provider version cannot be empty")
 	}
@@ -36,17 +38,79 @@ func Test_ProviderInfo(t *testing.T) {
 		t.Fatal("provider version sha may not be empty")
 	}
 
-	v := systeminfo.Version
-	if v == nil {
-		t.Fatal("gateway version may not be nil")
+	components := []struct {
+		name, release, sha string
+	}{
+		{name: "provider", release: pv.Release, sha: pv.SHA},
 	}
-	if v.Release == "" {
-		t.Fatal("gateway version release may not be empty")
+
+	// the provider API does not know the gateway version
+	gatewayRelease := ""
+	if config.Target == targetGateway {
+		if v == nil {
+			t.Fatal("gateway version may not be nil")
+		}
+		if v.Release == "" {
+			t.Fatal("gateway version release may not be empty")
+		}
+		if v.SHA == "" {
+			t.Fatal("gateway version sha may not be empty")
+		}
+
+		gatewayRelease = v.Release
+		components = append(components, struct{ name, release, sha string }{name: "gateway", release: v.Release, sha: v.SHA})
+		t.Logf("Info provider: %s, provider release version: %s, gateway release version: %s",
+			provider.Name, pv.Release, v.Release)
+	} else {
+		t.Logf("Info provider: %s, provider release version: %s", provider.Name, pv.Release)
 	}
-	if v.SHA == "" {
-		t.Fatal("gateway version sha may not be empty")
+
+	t.Run("orchestration is known or declared in the profile", func(t *testing.T) {
+		orchestration := provider.Orchestration
+		known := orchestration == config.Profile.Orchestration
+		for _, o := range knownOrchestrations {
+			known = known || orchestration == o
+		}
+		if !known {
+			t.Fatalf("unknown orchestration %q, wanted one of %v or the profile orchestration", orchestration, knownOrchestrations)
+		}
+	})
+
+	for _, c := range components {
+		c := c
+		t.Run(c.name+" release and sha", func(t *testing.T) {
+			release, err := version.Parse(c.release)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// dev builds may not know their commit
+			if release.Dev && c.sha == version.Dev {
+				return
+			}
+			if !version.ValidSHA(c.sha) {
+				t.Fatalf("sha %q is not a git commit SHA", c.sha)
+			}
+		})
 	}
 
-	t.Logf("Info provider: %s, provider release version: %s, gateway release version: %s",
-		systeminfo.Provider.Name, systeminfo.Provider.Version.Release, systeminfo.Version.Release)
+	t.Run("compatibility matrix", func(t *testing.T) {
+		ok, err := config.Compatibility.Check(provider.Name, pv.Release, gatewayRelease)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			skipf(t, "%s %s is not in the compatibility matrix", provider.Name, pv.Release)
+		}
+
+		// Check succeeds only for releases that parse
+		providerRelease, _ := version.Parse(pv.Release)
+		report.Combination = fmt.Sprintf("provider %s v%s", provider.Name, providerRelease)
+		if gatewayRelease != "" {
+			parsed, _ := version.Parse(gatewayRelease)
+			report.Combination += fmt.Sprintf(" with gateway v%s", parsed)
+		} else {
+			report.Combination += " via the provider API"
+		}
+		t.Logf("certifying %s", report.Combination)
+	})
 }
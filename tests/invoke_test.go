@@ -1,12 +1,12 @@
 package tests
 
 import (
+	"encoding/json"
 	"net/http"
-	"strings"
 	"testing"
 
-	"fmt"
-
+	echo "github.com/openfaas/certifier/functions/echo"
+	"github.com/openfaas/certifier/internal/images"
 	sdk "github.com/openfaas/faas-cli/proxy"
 	types "github.com/openfaas/faas-provider/types"
 )
@@ -20,29 +20,27 @@ func Test_InvokeNotFound(t *testing.T) {
 }
 
 func invokeWithSupportedVerbs(t *testing.T, functionRequest *sdk.DeployFunctionSpec) {
-	verbs := []struct {
-		verb  string
-		match func(string) bool
-	}{
-		{verb: http.MethodGet, match: func(r string) bool { return strings.Contains(r, "Http_Method=GET") }},
-		{verb: http.MethodPost, match: func(r string) bool { return strings.Contains(r, "Http_Method=POST") }},
-		{verb: http.MethodPut, match: func(r string) bool { return strings.Contains(r, "Http_Method=PUT") }},
-		{verb: http.MethodPatch, match: func(r string) bool { return strings.Contains(r, "Http_Method=PATCH") }},
-		{verb: http.MethodDelete, match: func(r string) bool { return strings.Contains(r, "Http_Method=DELETE") }},
+	verbs := []string{
+		http.MethodGet,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
 	}
 
-	for _, v := range verbs {
-		t.Run(v.verb, func(t *testing.T) {
+	for _, verb := range verbs {
+		t.Run(verb, func(t *testing.T) {
 
-			bytesOut, res := invokeWithVerb(t, v.verb, functionRequest, emptyQueryString, "", http.StatusOK)
+			bytesOut, res := invokeWithVerb(t, verb, functionRequest, emptyQueryString, "", http.StatusOK)
 
-			out := string(bytesOut)
-			if !v.match(out) {
-				t.Fatalf("want: %s, got: %s", fmt.Sprintf("Http_Method=%s", v.verb), out)
+			out := echoResponse(t, bytesOut)
+			if out.Method != verb {
+				t.Fatalf("want method: %s, got: %s", verb, out.Method)
 			}
 
+			// the gateway adds the call id
 			callID := res.Header.Get("X-Call-Id")
-			if callID == "" {
+			if callID == "" && config.Target == targetGateway {
 				t.Fatal("expect non-empty X-Call-Id header")
 			}
 
@@ -56,54 +54,59 @@ func invokeWithSupportedVerbs(t *testing.T, functionRequest *sdk.DeployFunctionS
 
 func invokeWithCustomEnvVarsAndQueryString(t *testing.T, functionRequest *sdk.DeployFunctionSpec) {
 	t.Run("Empty QueryString", func(t *testing.T) {
-		bytesOut := invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
-		out := string(bytesOut)
-		if strings.Contains(out, "custom_env") == false {
-			t.Fatalf("want: %s, got: %s", "custom_env", out)
+		out := echoResponse(t, invoke(t, functionRequest, emptyQueryString, "", http.StatusOK))
+		if got := out.Env["custom_env"]; got != "custom_env_value" {
+			t.Fatalf("want custom_env: %s, got: %q", "custom_env_value", got)
 		}
 	})
 
 	t.Run("Populated QueryString", func(t *testing.T) {
-		bytesOut := invoke(t, functionRequest, "testing=1", "", http.StatusOK)
-		out := string(bytesOut)
-		if strings.Contains(out, "Http_Query=testing=1") == false {
-			t.Fatalf("want: %s, got: %s", "Http_Query=testing=1", out)
+		out := echoResponse(t, invoke(t, functionRequest, "testing=1", "", http.StatusOK))
+		if out.Query != "testing=1" {
+			t.Fatalf("want query: %s, got: %q", "testing=1", out.Query)
 		}
 	})
 }
 
+// echoResponse parses the response of the echo fixture.
+func echoResponse(t *testing.T, body []byte) echo.Response {
+	t.Helper()
+
+	var res echo.Response
+	if err := json.Unmarshal(body, &res); err != nil {
+		t.Fatalf("invalid echo response %.200q: %s", body, err)
+	}
+	return res
+}
+
 func Test_Invoke(t *testing.T) {
 	t.Logf("Gateway: %s", config.Gateway)
-	imagePrefix := config.RegistryPrefix + "/"
 	cases := []FunctionTestCase{
 		{
 			name: "Invoke test with different verbs",
 			function: types.FunctionDeployment{
-				Image:      imagePrefix + "functions/alpine:latest",
-				Service:    "env-test-verbs",
-				EnvProcess: "env",
-				EnvVars:    map[string]string{},
-				Namespace:  config.DefaultNamespace,
+				Image:     fixtureImage(t, "echo"),
+				Service:   "env-test-verbs",
+				EnvVars:   map[string]string{},
+				Namespace: config.DefaultNamespace,
 			},
 		},
 		{
 			name: "Invoke propogates redirect to the caller",
 			function: types.FunctionDeployment{
-				Image:      imagePrefix + "theaxer/redirector:latest",
-				Service:    "redirector-test",
-				EnvProcess: "./handler",
-				EnvVars:    map[string]string{"destination": "http://example.com"},
-				Namespace:  config.DefaultNamespace,
+				Image:     fixtureImage(t, "redirector"),
+				Service:   "redirector-test",
+				EnvVars:   map[string]string{"destination": "http://example.com"},
+				Namespace: config.DefaultNamespace,
 			},
 		},
 		{
 			name: "Invoke with custom env vars and query string",
 			function: types.FunctionDeployment{
-				Image:      imagePrefix + "functions/alpine:latest",
-				Service:    "env-test",
-				EnvProcess: "env",
-				EnvVars:    map[string]string{"custom_env": "custom_env_value"},
-				Namespace:  config.DefaultNamespace,
+				Image:     fixtureImage(t, "echo"),
+				Service:   "env-test",
+				EnvVars:   map[string]string{"custom_env": "custom_env_value"},
+				Namespace: config.DefaultNamespace,
 			},
 		},
 	}
@@ -134,10 +137,8 @@ func Test_Invoke(t *testing.T) {
 
 			status := get(t, functionRequest.FunctionName, functionRequest.Namespace)
 
-			if status.Image != functionRequest.Image {
-				t.Fatalf("function status image - want %s, but got %s",
-					functionRequest.Image,
-					status.Image)
+			if err := images.Compare(functionRequest.Image, status.Image, config.Profile.ImageComparison); err != nil {
+				t.Fatalf("function status image - %s", err)
 			}
 
 			switch service := c.function.Service; service {
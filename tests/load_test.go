@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openfaas/certifier/internal/load"
+	sdk "github.com/openfaas/faas-cli/proxy"
+	"github.com/rakyll/hey/requester"
+)
+
+// replicaSampleInterval is how often the replicas are sampled during load
+const replicaSampleInterval = time.Second
+
+// loadResult is attached to the report of every test that generates load.
+type loadResult struct {
+	Load load.Summary `json:"load"`
+	// Replicas is the time series of the function replicas during the load
+	Replicas []replicaSample `json:"replicas"`
+}
+
+type replicaSample struct {
+	// Seconds since the load started
+	Seconds           float64 `json:"seconds"`
+	Replicas          uint64  `json:"replicas"`
+	AvailableReplicas uint64  `json:"availableReplicas"`
+}
+
+func (r loadResult) String() string {
+	var samples []string
+	for _, s := range r.Replicas {
+		samples = append(samples, fmt.Sprintf("%.0fs:%d/%d", s.Seconds, s.AvailableReplicas, s.Replicas))
+	}
+	return fmt.Sprintf("%s\nreplicas (available/requested): %s", r.Load, strings.Join(samples, " "))
+}
+
+// functionLoad invokes a function with hey while sampling its replicas.
+type functionLoad struct {
+	run     *load.Run
+	cancel  context.CancelFunc
+	sampled chan struct{}
+
+	mu      sync.Mutex
+	samples []replicaSample
+}
+
+// startLoad sends the load described by work to the function, the request is
+// set by startLoad. Stop or Wait for the load to get the result.
+func startLoad(t *testing.T, function *sdk.DeployFunctionSpec, work *requester.Work) *functionLoad {
+	t.Helper()
+
+	functionURL := resourceURL(t, path.Join("function", fmt.Sprintf("%s.%s", function.FunctionName, function.Namespace)), "")
+	req, err := http.NewRequest(http.MethodPost, functionURL, nil)
+	if err != nil {
+		t.Fatalf("error with request %s ", err)
+	}
+	work.Request = req
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &functionLoad{cancel: cancel, sampled: make(chan struct{})}
+
+	start := time.Now()
+	go func() {
+		defer close(l.sampled)
+
+		ticker := time.NewTicker(replicaSampleInterval)
+		defer ticker.Stop()
+
+		for {
+			status, err := config.Client.GetFunctionInfo(ctx, function.FunctionName, function.Namespace)
+			if err == nil {
+				l.mu.Lock()
+				l.samples = append(l.samples, replicaSample{
+					Seconds:           time.Since(start).Round(time.Second).Seconds(),
+					Replicas:          status.Replicas,
+					AvailableReplicas: status.AvailableReplicas,
+				})
+				l.mu.Unlock()
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	l.run = load.Start(work)
+	return l
+}
+
+// Stop stops the load and records the result in the report.
+func (l *functionLoad) Stop(t *testing.T) loadResult {
+	t.Helper()
+
+	summary, err := l.run.Stop()
+	return l.finish(t, summary, err)
+}
+
+// Wait waits for all requests to be sent and records the result in the
+// report.
+func (l *functionLoad) Wait(t *testing.T) loadResult {
+	t.Helper()
+
+	summary, err := l.run.Wait()
+	return l.finish(t, summary, err)
+}
+
+func (l *functionLoad) finish(t *testing.T, summary load.Summary, err error) loadResult {
+	t.Helper()
+
+	l.cancel()
+	<-l.sampled
+
+	if err != nil {
+		t.Fatalf("load generation failed: %s", err)
+	}
+
+	l.mu.Lock()
+	result := loadResult{Load: summary, Replicas: l.samples}
+	l.mu.Unlock()
+
+	report.Add(t.Name(), "load", result)
+	return result
+}
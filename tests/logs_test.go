@@ -14,25 +14,19 @@ import (
 
 func Test_FunctionLogs(t *testing.T) {
 	type logsTestCase struct {
-		name         string
-		function     sdk.DeployFunctionSpec
-		expectedLogs []string
+		name     string
+		function sdk.DeployFunctionSpec
 	}
 
 	cases := []logsTestCase{
 		{
 			name: "provider can stream logs",
 			function: sdk.DeployFunctionSpec{
-				Image:        "functions/alpine:latest",
+				Image:        fixtureImage(t, "logger"),
 				FunctionName: "test-logger",
 				Network:      "func_functions",
-				FProcess:     "cat",
 				Namespace:    config.DefaultNamespace,
 			},
-			expectedLogs: []string{
-				"Forking fprocess",
-				fmt.Sprintf("Wrote %d Bytes", len(config.DefaultNamespace)),
-			},
 		},
 	}
 
@@ -40,14 +34,7 @@ func Test_FunctionLogs(t *testing.T) {
 		cnCases := make([]logsTestCase, len(cases))
 		copy(cnCases, cases)
 		for index := 0; index < len(cnCases); index++ {
-			ns := config.Namespaces[0]
-
-			newLogs := make([]string, len(cnCases[index].expectedLogs))
-			copy(newLogs, cnCases[index].expectedLogs)
-
-			newLogs[1] = fmt.Sprintf("Wrote %d Bytes", len(ns))
-			cnCases[index].expectedLogs = newLogs
-			cnCases[index].function.Namespace = ns
+			cnCases[index].function.Namespace = config.Namespaces[0]
 		}
 
 		cases = append(cases, cnCases...)
@@ -63,10 +50,6 @@ func Test_FunctionLogs(t *testing.T) {
 				t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
 			}
 
-			// each invoke should output two lines
-			// - Forking fprocess.
-			// - Wrote 132 Bytes - Duration: ...
-
 			ns := c.function.Namespace
 			if ns == "" {
 				ns = config.DefaultNamespace
@@ -77,9 +60,12 @@ func Test_FunctionLogs(t *testing.T) {
 				t.Fatalf("Function %q failed to start: %s", c.function.FunctionName, err)
 			}
 
-			data := invoke(t, &c.function, "", ns, http.StatusOK)
-			if string(data) != ns {
-				t.Fatalf("got invoke response %s, expected %s", string(data), ns)
+			// the logger writes each line of the body to stderr, a line
+			// per namespace tells the cases apart
+			line := fmt.Sprintf("log line from %s", ns)
+			data := invoke(t, &c.function, "", line, http.StatusOK)
+			if string(data) != "logged 1 lines" {
+				t.Fatalf("got invoke response %s, expected %s", string(data), "logged 1 lines")
 			}
 
 			time.Sleep(30 * time.Second)
@@ -113,10 +99,8 @@ func Test_FunctionLogs(t *testing.T) {
 				logLines = append(logLines, msg)
 			}
 
-			for _, want := range c.expectedLogs {
-				if !checkIfLogIsRecorded(logLines, want) {
-					t.Fatalf("Want log message %q, but were not recorded", want)
-				}
+			if want := "stderr: " + line; !checkIfLogIsRecorded(logLines, want) {
+				t.Fatalf("Want log message %q, but were not recorded", want)
 			}
 		})
 	}
@@ -13,6 +13,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openfaas/certifier/internal/fixtures"
+	"github.com/openfaas/certifier/internal/images"
+	"github.com/openfaas/certifier/internal/version"
 	sdkConfig "github.com/openfaas/faas-cli/config"
 
 	sdk "github.com/openfaas/faas-cli/proxy"
@@ -23,6 +26,9 @@ var (
 	token             = flag.String("token", "", "authentication Bearer token override, enables auth automatically")
 	faasdProviderName = "faasd"
 	// faasNetesProviderName = "faas-netes"
+
+	// secretScanner is the transport of config.Client
+	secretScanner = &leakScanner{watchRequests: true}
 )
 
 func init() {
@@ -37,7 +43,23 @@ func init() {
 	)
 	flag.BoolVar(&config.SecretUpdate, "secretUpdate", true, "enable/disable secret update tests")
 	flag.BoolVar(&config.EnableScaling, "enableScaling", true, "enable/disable scale  tests")
+	flag.DurationVar(&config.ScaleTimeout, "scaleTimeout", 2*time.Minute, "time allowed for available replicas to converge after scaling")
+	flag.DurationVar(&config.DeleteTimeout, "deleteTimeout", time.Minute, "time allowed for a deleted function to disappear from describe, list and invoke")
+	flag.IntVar(&config.Stress, "stress", 0, "number of functions deployed, updated, scaled and deleted concurrently by the management API stress test, disabled when 0")
+	flag.IntVar(&config.StressConcurrency, "stressConcurrency", 50, "number of concurrent management API calls made by the stress test")
+	flag.IntVar(&config.ColdStartCycles, "coldStartCycles", 5, "number of scale to zero and invoke cycles used to measure the cold start latency")
+	flag.DurationVar(&config.ColdStartSLO, "coldStartSLO", 0, "fail when the p95 cold start latency is above this value, disabled when 0")
+	flag.StringVar(&config.ReportPath, "report", "", "write the measurements of the run as JSON to this file")
 	flag.StringVar(&config.RegistryPrefix, "registryPrefix", "docker.io", "provide custom registry path")
+	flag.StringVar(&config.ImageConfig, "images", "", "JSON file with per-image overrides and pinned digests")
+	flag.StringVar(&config.FixturesDir, "fixturesDir", filepath.Join("..", "build", "fixtures"), "output folder of the certifier fixtures build command")
+	flag.StringVar(&config.PrivateRegistry, "privateRegistry", "", "address of the private registry stand-in as seen by the provider, e.g. 192.168.1.10:5001, enables the pull secret tests")
+	flag.StringVar(&config.PrivateRegistryListen, "privateRegistryListen", ":5001", "listen address of the private registry stand-in")
+	flag.StringVar(&config.PullSecretCommand, "pullSecretCommand", "", "script that installs (create) or removes (delete) registry credentials for the provider, e.g. contrib/pull_secret_kubernetes.sh")
+	flag.StringVar(&config.ProfilePath, "profile", "", "provider profile JSON file, defaults to ../profiles/<provider>.json when it exists")
+	flag.StringVar(&config.Target, "target", targetGateway, "API to certify: gateway, or provider to point the checks at the faas-provider HTTP surface given by -gateway, skipping gateway-only checks")
+	flag.StringVar(&config.CompatibilityPath, "compatibility", filepath.Join("..", "compatibility.json"), "compatibility matrix with the minimum provider and gateway releases")
+	flag.StringVar(&config.FixturesStack, "fixtures", filepath.Join("..", "functions", "stack.yml"), "path to the fixture functions stack file")
 
 	FromEnv(&config)
 }
@@ -64,6 +86,10 @@ func TestMain(m *testing.M) {
 
 	config.Gateway = uri.String()
 
+	if config.Target != targetGateway && config.Target != targetProvider {
+		log.Fatalf("-target must be %q or %q, got %q", targetGateway, targetProvider, config.Target)
+	}
+
 	// make sure to trim any trailing slash because this is how the gateway is modified when
 	// saved to the config. if we don't do this, we wont find the saved auth.
 	config.Gateway = strings.TrimRight(config.Gateway, "/")
@@ -77,8 +103,10 @@ func TestMain(m *testing.M) {
 		}
 	}
 
+	// every response of the run is scanned for the secret values the tests
+	// send, a leak fails the run
 	timeout := 30 * time.Second
-	config.Client, err = sdk.NewClient(config.Auth, config.Gateway, nil, &timeout)
+	config.Client, err = sdk.NewClient(config.Auth, config.Gateway, secretScanner, &timeout)
 	if err != nil {
 		log.Fatalf("can not client: %s", err)
 	}
@@ -95,21 +123,71 @@ func TestMain(m *testing.M) {
 
 	config.SupportCPULimits = config.ProviderName != faasdProviderName
 
+	config.Profile, err = loadProfile(config.ProfilePath, filepath.Join("..", "profiles"), config.ProviderName)
+	if err != nil {
+		log.Fatalf("Can not load profile: %s", err)
+	}
+
+	config.Compatibility, err = version.LoadMatrix(config.CompatibilityPath)
+	if err != nil {
+		log.Fatalf("Can not load compatibility matrix: %s", err)
+	}
+
+	config.Fixtures, err = loadFixtures(config.FixturesStack)
+	if err != nil {
+		log.Fatalf("Can not load fixtures: %s", err)
+	}
+
+	config.Images, err = images.LoadResolver(config.RegistryPrefix, config.ImageConfig)
+	if err != nil {
+		log.Fatalf("Can not load image config: %s", err)
+	}
+
 	prettyConfig, err := json.MarshalIndent(config, "", "\t")
 	if err != nil {
 		log.Fatalf("Config Pretty Print Failed with %s", err)
 	}
 	log.Println(string(prettyConfig))
 
-	os.Exit(m.Run())
+	report.Provider = config.ProviderName
+	report.Gateway = config.Gateway
+
+	code := m.Run()
+
+	leaks := secretScanner.Leaks()
+	report.Add("TestMain", "scannedResponses", secretScanner.Responses())
+	report.Add("TestMain", "secretLeaks", leaks)
+	for _, leak := range leaks {
+		log.Printf("secret leak: %s", leak)
+	}
+	if len(leaks) > 0 && code == 0 {
+		code = 1
+	}
+
+	// only a full run certifies the combination
+	report.Run = flag.Lookup("test.run").Value.String()
+	if code == 0 && report.Run == "" {
+		report.Certified = report.Combination
+	}
+
+	if config.ReportPath != "" {
+		if err := report.WriteFile(config.ReportPath); err != nil {
+			log.Fatalf("Can not write report: %s", err)
+		}
+	}
+
+	os.Exit(code)
 }
 
 // Config contains the configuration values for the certifier tests
 // This includes the gateway and auth parameters as well as the feature
 // flags to control skipping specific tests.
 type Config struct {
-	// Gateway is the URL for the gateway that will be tested
+	// Gateway is the URL for the gateway that will be tested, or the
+	// provider with -target=provider
 	Gateway string
+	// Target is the API under test, targetGateway or targetProvider
+	Target string
 	// Auth contains the parsed proxy client auth
 	Auth sdk.ClientAuth
 	// Client is a preconfigured gateway client, including auth
@@ -131,11 +209,60 @@ type Config struct {
 	ProviderName string
 	//EnableScale will enable scaling test cases
 	EnableScaling bool
+	// ScaleTimeout is the convergence budget for AvailableReplicas
+	ScaleTimeout time.Duration
+	// DeleteTimeout is the convergence budget for deleting a function
+	DeleteTimeout time.Duration
+	// ColdStartCycles is the number of cold starts measured
+	ColdStartCycles int
+	// ColdStartSLO fails the cold start test when the p95 latency exceeds it
+	ColdStartSLO time.Duration
+
+	// Stress is the number of functions used by the management API stress
+	// test, it is skipped when 0
+	Stress int
+	// StressConcurrency is the number of concurrent stress test API calls
+	StressConcurrency int
+
+	// ReportPath is the file the measurements are written to, see Report
+	ReportPath string
 
 	// registry prefix for private registry
 	RegistryPrefix string
 
+	// ImageConfig is the optional JSON file with image overrides and digests
+	ImageConfig string
+	// Images resolves every image deployed by the tests, see resolveImage
+	Images *images.Resolver
+
 	SupportCPULimits bool
+
+	// ProfilePath is the provider profile file, see Profile
+	ProfilePath string
+	// Profile declares the provider behaviour the checks assert
+	Profile Profile
+
+	// CompatibilityPath is the compatibility matrix file
+	CompatibilityPath string
+	// Compatibility lists the minimum provider and gateway releases
+	Compatibility version.Matrix
+
+	// FixturesStack is the stack file describing the fixture functions
+	FixturesStack string
+	// Fixtures maps the fixture function name to its image, without the
+	// registry prefix
+	Fixtures map[string]string
+	// FixturesDir contains the fixture image tarballs
+	FixturesDir string
+
+	// PrivateRegistry is the address the provider uses to reach the
+	// authenticated registry stand-in, the tests are skipped when empty
+	PrivateRegistry string
+	// PrivateRegistryListen is where the stand-in listens
+	PrivateRegistryListen string
+	// PullSecretCommand installs the registry credentials using the
+	// provider's documented pull secret mechanism
+	PullSecretCommand string
 }
 
 func FromEnv(config *Config) {
@@ -168,14 +295,27 @@ func FromEnv(config *Config) {
 	}
 }
 
+func loadFixtures(stackFile string) (map[string]string, error) {
+	list, err := fixtures.Load(stackFile, "")
+	if err != nil {
+		return nil, err
+	}
+
+	images := map[string]string{}
+	for _, f := range list {
+		images[f.Name] = f.Image
+	}
+	return images, nil
+}
+
 func getProvider(client *sdk.Client) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	info, err := client.GetSystemInfo(ctx)
+	info, _, err := systemInfo(ctx, client)
 	if err != nil {
 		return "", err
 	}
 
-	return info.Provider.Name, nil
+	return info.Name, nil
 }
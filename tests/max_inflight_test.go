@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+	"github.com/rakyll/hey/requester"
+)
+
+// inflightSleep is how long the sleep fixture holds every request
+const inflightSleep = time.Second
+
+func Test_MaxInflightBackPressure(t *testing.T) {
+	functionName := "test-max-inflight"
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "sleep"),
+		FunctionName: functionName,
+		Network:      "func_functions",
+		EnvVars: map[string]string{
+			"max_inflight":   "1",
+			"sleep_duration": inflightSleep.String(),
+		},
+		// a single replica, so that requests can only run one at a time
+		Labels: map[string]string{
+			"com.openfaas.scale.min": "1",
+			"com.openfaas.scale.max": "1",
+		},
+		Namespace: config.DefaultNamespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err := waitForFunctionStatus(time.Minute, functionName, config.DefaultNamespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", functionName, err)
+	}
+
+	// the first invoke may still race the replica becoming routable
+	_ = invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+
+	result := startLoad(t, functionRequest, &requester.Work{
+		N:                 100,
+		C:                 10,
+		Timeout:           30,
+		DisableKeepAlives: true,
+	}).Wait(t)
+	summary := result.Load
+
+	t.Logf("function load %s", summary)
+
+	if len(summary.Errors) > 0 {
+		t.Fatalf("want every request answered, got errors %v, saturated requests must not hang", summary.Errors)
+	}
+
+	for code, count := range summary.StatusCodes {
+		if code != http.StatusOK && code != http.StatusTooManyRequests {
+			t.Fatalf("got %d responses with %d, wanted only %d or %d", count, code, http.StatusOK, http.StatusTooManyRequests)
+		}
+	}
+
+	if summary.StatusCodes[http.StatusTooManyRequests] == 0 {
+		t.Fatalf("no request was rejected with %d by a saturated function", http.StatusTooManyRequests)
+	}
+
+	served := summary.StatusCodes[http.StatusOK]
+	if served == 0 {
+		t.Fatalf("no request succeeded")
+	}
+
+	// served one at a time, every request holds the replica for
+	// inflightSleep, so the run can not serve more than fit end to end
+	elapsed := time.Duration(float64(summary.Requests) / summary.RPS * float64(time.Second))
+	if limit := int(elapsed/inflightSleep) + 1; served > limit {
+		t.Fatalf("served %d requests of %s in %s, wanted at most %d one at a time",
+			served, inflightSleep, elapsed.Round(time.Millisecond), limit)
+	}
+}
@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+)
+
+// The limits of Kubernetes labels, which are the strictest of the known
+// providers: a key name and a value have at most 63 characters and an
+// optional DNS subdomain prefix at most 253.
+const (
+	labelNameMaxLength   = 63
+	labelPrefixMaxLength = 253
+	labelValueMaxLength  = 63
+)
+
+func Test_LabelsAndAnnotationsEdgeCases(t *testing.T) {
+	labels := map[string]string{
+		strings.Repeat("k", labelNameMaxLength):                        "max-length-key",
+		"max-length-value":                                             strings.Repeat("v", labelValueMaxLength),
+		"example.com/prefixed":                                         "prefixed-key",
+		dnsSubdomain(labelPrefixMaxLength) + "/" + "max-length-prefix": "max-length-prefix",
+		"allowed_chars-in.key":                                         "Allowed_chars-in.value",
+		"empty-value":                                                  "",
+		"com.openfaas.scale.min":                                       "1",
+		"com.openfaas.scale.max":                                       "2",
+	}
+	annotations := map[string]string{
+		"example.com/prefixed":            "prefixed-key",
+		"unicode":                         "héllo wörld ✓ 日本語 🚀",
+		"multi-line":                      "line one\nline two\n",
+		"large":                           strings.Repeat("0123456789abcdef", 2048),
+		"com.openfaas.certifier.reserved": "reserved namespace",
+	}
+
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "hash"),
+		FunctionName: "test-metadata-edge-cases",
+		Network:      "func_functions",
+		Labels:       labels,
+		Annotations:  annotations,
+		Namespace:    config.DefaultNamespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	// the reserved keys must not break the function
+	_ = invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+
+	t.Run("GetFunctionInfo returns the values as submitted", func(t *testing.T) {
+		assertMetadata(t, functionRequest)
+	})
+
+	t.Run("update removes keys", func(t *testing.T) {
+		update := *functionRequest
+		update.Update = true
+		update.Labels = map[string]string{
+			"example.com/prefixed": "updated",
+		}
+		update.Annotations = map[string]string{
+			"unicode": "updated ✓",
+		}
+
+		deployStatus := deploy(t, &update)
+		if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+			t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+		}
+
+		assertMetadata(t, &update)
+
+		status := get(t, update.FunctionName, update.Namespace)
+		for key := range labels {
+			if _, ok := update.Labels[key]; ok || status.Labels == nil {
+				continue
+			}
+			if _, ok := (*status.Labels)[key]; ok {
+				t.Errorf("removed label %.80s is still reported", key)
+			}
+		}
+		for key := range annotations {
+			if _, ok := update.Annotations[key]; ok || status.Annotations == nil {
+				continue
+			}
+			if _, ok := (*status.Annotations)[key]; ok {
+				t.Errorf("removed annotation %s is still reported", key)
+			}
+		}
+	})
+}
+
+func Test_InvalidLabels(t *testing.T) {
+	cases := []struct {
+		name   string
+		labels map[string]string
+	}{
+		{
+			name:   "invalid characters in key",
+			labels: map[string]string{"invalid key!": "value"},
+		},
+		{
+			name:   "invalid characters in value",
+			labels: map[string]string{"key": "invalid value ✓"},
+		},
+		{
+			name:   "key above the length limit",
+			labels: map[string]string{strings.Repeat("k", labelNameMaxLength+1): "value"},
+		},
+		{
+			name:   "value above the length limit",
+			labels: map[string]string{"key": strings.Repeat("v", labelValueMaxLength+1)},
+		},
+		{
+			name:   "empty key",
+			labels: map[string]string{"": "value"},
+		},
+	}
+
+	for i, tc := range cases {
+		tc := tc
+		name := fmt.Sprintf("test-metadata-invalid-%d", i)
+		t.Run(tc.name, func(t *testing.T) {
+			functionRequest := &sdk.DeployFunctionSpec{
+				Image:        fixtureImage(t, "hash"),
+				FunctionName: name,
+				Network:      "func_functions",
+				Labels:       tc.labels,
+				Namespace:    config.DefaultNamespace,
+			}
+
+			deployStatus := tryDeploy(functionRequest)
+			if deployStatus < http.StatusBadRequest {
+				defer deleteFunction(t, functionRequest)
+			}
+
+			switch config.Profile.Labels.Validation {
+			case labelValidationPassthrough:
+				if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+					t.Fatalf("got %d, wanted %d or %d, the profile declares labels.validation %q",
+						deployStatus, http.StatusOK, http.StatusAccepted, labelValidationPassthrough)
+				}
+				assertMetadata(t, functionRequest)
+			default:
+				if deployStatus < http.StatusBadRequest || deployStatus >= http.StatusInternalServerError {
+					t.Fatalf("got %d, wanted a 4xx status for invalid labels, the profile declares labels.validation %q",
+						deployStatus, labelValidationReject)
+				}
+			}
+		})
+	}
+}
+
+// assertMetadata fails the test when GetFunctionInfo does not return the
+// labels and annotations as submitted. Keys the provider added are logged
+// separately, they are not a mismatch.
+func assertMetadata(t *testing.T, function *sdk.DeployFunctionSpec) {
+	t.Helper()
+
+	status := get(t, function.FunctionName, function.Namespace)
+
+	var gotLabels, gotAnnotations map[string]string
+	if status.Labels != nil {
+		gotLabels = *status.Labels
+	}
+	if status.Annotations != nil {
+		gotAnnotations = *status.Annotations
+	}
+
+	labelMismatches, addedLabels := strMapDiff(gotLabels, function.Labels)
+	annotationMismatches, addedAnnotations := strMapDiff(gotAnnotations, function.Annotations)
+
+	if len(addedLabels) > 0 || len(addedAnnotations) > 0 {
+		t.Logf("provider added labels %v and annotations %v", addedLabels, addedAnnotations)
+	}
+
+	for _, mismatch := range labelMismatches {
+		t.Errorf("label %s", mismatch)
+	}
+	for _, mismatch := range annotationMismatches {
+		t.Errorf("annotation %s", mismatch)
+	}
+}
+
+// dnsSubdomain returns a valid DNS subdomain of exactly length characters.
+func dnsSubdomain(length int) string {
+	var b strings.Builder
+	for b.Len() < length {
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+
+		n := length - b.Len()
+		if n > labelNameMaxLength {
+			n = labelNameMaxLength
+		}
+		// a single character would be left for the next label and its dot
+		if length-b.Len()-n == 1 {
+			n--
+		}
+		b.WriteString(strings.Repeat("d", n))
+	}
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+)
+
+// nameOutcome is what a provider must do with a function or namespace name
+type nameOutcome string
+
+const (
+	nameAccepted nameOutcome = "accepted"
+	nameRejected nameOutcome = "rejected"
+	// nameEither leaves the choice to the provider, but an accepted name must
+	// still be routable
+	nameEither nameOutcome = "either"
+)
+
+type nameTestCase struct {
+	name      string
+	function  string
+	namespace string
+	want      nameOutcome
+}
+
+func Test_FunctionNameValidation(t *testing.T) {
+	namespace := config.DefaultNamespace
+	cases := []nameTestCase{
+		{name: "lower case letters, digits and hyphens", function: "test-name-valid-1", want: nameAccepted},
+		{name: "63 characters", function: "test-name-" + strings.Repeat("a", 53), want: nameAccepted},
+		{name: "64 characters", function: "test-name-" + strings.Repeat("a", 54), want: nameRejected},
+		{name: "upper case", function: "Test-Name-Upper", want: nameRejected},
+		{name: "underscore", function: "test_name_underscore", want: nameRejected},
+		// the invoke path /function/name.namespace can not be split
+		// unambiguously when the name contains a dot
+		{name: "dots", function: "test.name.dots", want: nameRejected},
+		{name: "leading hyphen", function: "-test-name", want: nameRejected},
+		{name: "trailing hyphen", function: "test-name-", want: nameRejected},
+		{name: "empty", function: "", want: nameRejected},
+		{name: "leading digit", function: "1-test-name", want: nameEither},
+		{name: "reserved path system", function: "system", want: nameEither},
+		{name: "reserved path healthz", function: "healthz", want: nameEither},
+	}
+
+	for i := range cases {
+		cases[i].namespace = namespace
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			checkNameOutcome(t, tc)
+		})
+	}
+}
+
+func Test_NamespaceNameValidation(t *testing.T) {
+	cases := []nameTestCase{
+		{name: "default namespace", namespace: config.DefaultNamespace, want: nameAccepted},
+		{name: "upper case", namespace: "OpenFaaS-Fn", want: nameRejected},
+		{name: "dots", namespace: config.DefaultNamespace + ".dots", want: nameRejected},
+		{name: "64 characters", namespace: strings.Repeat("n", 64), want: nameRejected},
+		{name: "namespace that does not exist", namespace: "certifier-does-not-exist", want: nameRejected},
+		{name: "namespace not managed by OpenFaaS", namespace: "kube-system", want: nameRejected},
+	}
+
+	for _, namespace := range config.Namespaces {
+		cases = append(cases, nameTestCase{name: "CERTIFIER_NAMESPACES " + namespace, namespace: namespace, want: nameAccepted})
+	}
+
+	for i := range cases {
+		cases[i].function = "test-namespace-name"
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			checkNameOutcome(t, tc)
+		})
+	}
+}
+
+// checkNameOutcome deploys the function and checks the outcome. An accepted
+// name must be available, invokable via /function/name.namespace, described
+// and deleted.
+func checkNameOutcome(t *testing.T, tc nameTestCase) {
+	t.Helper()
+
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "hash"),
+		FunctionName: tc.function,
+		Network:      "func_functions",
+		Namespace:    tc.namespace,
+	}
+
+	deployStatus := tryDeploy(functionRequest)
+	accepted := deployStatus == http.StatusOK || deployStatus == http.StatusAccepted
+	switch {
+	case deployStatus >= http.StatusInternalServerError:
+		t.Fatalf("deploy of %q in %q got %d, wanted a 4xx status when it is rejected",
+			tc.function, tc.namespace, deployStatus)
+	case tc.want == nameRejected && accepted:
+		_, _ = deleteRequest(t, tc.function, tc.namespace)
+		t.Fatalf("deploy of %q in %q got %d, wanted it %s", tc.function, tc.namespace, deployStatus, tc.want)
+	case tc.want == nameAccepted && !accepted:
+		t.Fatalf("deploy of %q in %q got %d, wanted %d or %d",
+			tc.function, tc.namespace, deployStatus, http.StatusOK, http.StatusAccepted)
+	case !accepted:
+		t.Logf("rejected with %d", deployStatus)
+		return
+	}
+
+	deleted := false
+	defer func() {
+		if !deleted {
+			_, _ = deleteRequest(t, tc.function, tc.namespace)
+		}
+	}()
+
+	err := waitForFunctionStatus(time.Minute, tc.function, tc.namespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("accepted function %q failed to start: %s", tc.function, err)
+	}
+
+	_ = invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+
+	uri := resourceURL(t, path.Join("system", "function", tc.function), "namespace="+tc.namespace)
+	body, res := request(t, uri, http.MethodGet, config.Auth, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("describe got %d, wanted %d: %s", res.StatusCode, http.StatusOK, body)
+	}
+
+	statusCode, out := deleteRequest(t, tc.function, tc.namespace)
+	if statusCode != http.StatusOK && statusCode != http.StatusAccepted {
+		t.Fatalf("delete got %d, wanted %d or %d: %s", statusCode, http.StatusOK, http.StatusAccepted, out)
+	}
+	deleted = true
+}
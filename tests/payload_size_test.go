@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+
+	hash "github.com/openfaas/certifier/functions/hash"
+	sdk "github.com/openfaas/faas-cli/proxy"
+)
+
+const mb = 1 << 20
+
+// payloadTimeout allows for slow links, 50 MB must get through both ways
+const payloadTimeout = 2 * time.Minute
+
+func Test_PayloadSizes(t *testing.T) {
+	functionName := "test-payload-size"
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "hash"),
+		FunctionName: functionName,
+		Network:      "func_functions",
+		EnvVars: map[string]string{
+			"read_timeout":  payloadTimeout.String(),
+			"write_timeout": payloadTimeout.String(),
+		},
+		Namespace: config.DefaultNamespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err := waitForFunctionStatus(time.Minute, functionName, config.DefaultNamespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", functionName, err)
+	}
+	_ = invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+
+	limits := config.Profile.Limits
+	requestSizes := []int64{1 * mb, 10 * mb, 50 * mb}
+	responseSizes := []int64{1 * mb, 10 * mb, 50 * mb}
+	if limits.RequestBytes > 0 {
+		requestSizes = append(requestSizes, limits.RequestBytes+1)
+	}
+	if limits.ResponseBytes > 0 {
+		responseSizes = append(responseSizes, limits.ResponseBytes+1)
+	}
+
+	for _, size := range requestSizes {
+		size := size
+		limited := limits.RequestBytes > 0 && size > limits.RequestBytes
+		t.Run(fmt.Sprintf("request body of %d bytes", size), func(t *testing.T) {
+			body := make([]byte, size)
+			rand.New(rand.NewSource(size)).Read(body)
+			want := sha512.Sum512(body)
+
+			res, out := invokePayload(t, functionRequest, "", body)
+			if limited {
+				if res.StatusCode != http.StatusRequestEntityTooLarge {
+					t.Fatalf("got %d, wanted %d for a body above the %d bytes limit", res.StatusCode, http.StatusRequestEntityTooLarge, limits.RequestBytes)
+				}
+				return
+			}
+
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("got %d, wanted %d: %.200s", res.StatusCode, http.StatusOK, out)
+			}
+
+			var got hash.Response
+			if err := json.Unmarshal(out, &got); err != nil {
+				t.Fatalf("invalid hash response %.200q: %s", out, err)
+			}
+			if got.Bytes != size || got.SHA512 != hex.EncodeToString(want[:]) {
+				t.Fatalf("function received %d bytes with SHA-512 %.16s..., sent %d bytes with %.16s..., the body was truncated or altered",
+					got.Bytes, got.SHA512, size, hex.EncodeToString(want[:]))
+			}
+		})
+	}
+
+	for _, size := range responseSizes {
+		size := size
+		limited := limits.ResponseBytes > 0 && size > limits.ResponseBytes
+		t.Run(fmt.Sprintf("response body of %d bytes", size), func(t *testing.T) {
+			res, out := invokePayload(t, functionRequest, fmt.Sprintf("size=%d", size), nil)
+			if limited {
+				if res.StatusCode != http.StatusRequestEntityTooLarge {
+					t.Fatalf("got %d, wanted %d for a response above the %d bytes limit", res.StatusCode, http.StatusRequestEntityTooLarge, limits.ResponseBytes)
+				}
+				return
+			}
+
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("got %d, wanted %d: %.200s", res.StatusCode, http.StatusOK, out)
+			}
+
+			sum := sha512.New()
+			if _, err := io.CopyN(sum, hash.Pattern(), size); err != nil {
+				t.Fatal(err)
+			}
+			want := hex.EncodeToString(sum.Sum(nil))
+			got := sha512.Sum512(out)
+
+			if int64(len(out)) != size || hex.EncodeToString(got[:]) != want {
+				t.Fatalf("received %d bytes with SHA-512 %.16s..., the function sent %d bytes with %.16s..., the body was truncated or altered",
+					len(out), hex.EncodeToString(got[:]), size, want)
+			}
+		})
+	}
+}
+
+// invokePayload invokes the function once, without retries, and returns the
+// full response body.
+func invokePayload(t *testing.T, function *sdk.DeployFunctionSpec, query string, body []byte) (*http.Response, []byte) {
+	t.Helper()
+
+	uri := resourceURL(t, path.Join("function", fmt.Sprintf("%s.%s", function.FunctionName, function.Namespace)), query)
+
+	client := http.Client{Timeout: payloadTimeout}
+	start := time.Now()
+	res, err := client.Post(uri, "application/octet-stream", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("invoke failed after %s: %s", time.Since(start).Round(time.Millisecond), err)
+	}
+	defer res.Body.Close()
+
+	out, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("reading the response failed after %d bytes: %s", len(out), err)
+	}
+
+	t.Logf("%d bytes sent, %d bytes received in %s", len(body), len(out), time.Since(start).Round(time.Millisecond))
+	return res, out
+}
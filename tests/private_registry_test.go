@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/certifier/internal/oci"
+	"github.com/openfaas/certifier/internal/registry/registrytest"
+	sdk "github.com/openfaas/faas-cli/proxy"
+)
+
+const privateRepository = "certifier/private-echo"
+
+func Test_PrivateRegistryPullSecret(t *testing.T) {
+	if config.PrivateRegistry == "" || config.PullSecretCommand == "" {
+		skipf(t, "set -privateRegistry and -pullSecretCommand to test private registry pulls")
+	}
+
+	username, password := "certifier", RandString(32)
+	reg := registrytest.New(username, password)
+
+	listener, err := net.Listen("tcp", config.PrivateRegistryListen)
+	if err != nil {
+		t.Fatalf("unable to start the registry stand-in on %s: %s", config.PrivateRegistryListen, err)
+	}
+
+	server := &http.Server{Handler: reg}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Close()
+
+	// a new tag for every run, so a cached image on the node can never
+	// satisfy the pull
+	tag := RandString(12)
+	loadPrivateImage(t, reg, tag)
+	image := config.PrivateRegistry + "/" + privateRepository + ":" + tag
+
+	t.Logf("registry stand-in listening on %s, serving %s", listener.Addr(), image)
+
+	t.Run("deploy without credentials is not available", func(t *testing.T) {
+		functionRequest := &sdk.DeployFunctionSpec{
+			Image:        image,
+			FunctionName: "private-registry-no-auth",
+			Namespace:    config.DefaultNamespace,
+		}
+
+		deployStatus := tryDeploy(functionRequest)
+		if deployStatus >= 400 {
+			t.Logf("deployment rejected with %d", deployStatus)
+			return
+		}
+		defer deleteFunction(t, functionRequest)
+
+		if !statusNeverReached(t, time.Minute, functionRequest.FunctionName, functionRequest.Namespace, minAvailableReplicaCount(1)) {
+			t.Fatalf("function %s became available without registry credentials", functionRequest.FunctionName)
+		}
+
+		if !pulledWith(reg, tag, false) {
+			t.Fatalf("the provider never tried to pull %s, check that %s is reachable from the cluster", image, config.PrivateRegistry)
+		}
+	})
+
+	t.Run("deploy with pull secret", func(t *testing.T) {
+		env := []string{
+			"CERTIFIER_REGISTRY=" + config.PrivateRegistry,
+			"CERTIFIER_REGISTRY_USERNAME=" + username,
+			"CERTIFIER_REGISTRY_PASSWORD=" + password,
+			"CERTIFIER_NAMESPACE=" + config.DefaultNamespace,
+		}
+
+		runPullSecretCommand(t, "create", env)
+		defer runPullSecretCommand(t, "delete", env)
+
+		functionRequest := &sdk.DeployFunctionSpec{
+			Image:        image,
+			FunctionName: "private-registry-auth",
+			Namespace:    config.DefaultNamespace,
+		}
+
+		deployStatus := deploy(t, functionRequest)
+		if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+			t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+		}
+		defer deleteFunction(t, functionRequest)
+
+		err := waitForFunctionStatus(2*time.Minute, functionRequest.FunctionName, functionRequest.Namespace, minAvailableReplicaCount(1))
+		if err != nil {
+			t.Fatalf("function %s failed to start with registry credentials: %s", functionRequest.FunctionName, err)
+		}
+
+		out := string(invoke(t, functionRequest, emptyQueryString, "", http.StatusOK))
+		if !strings.Contains(out, `"method":"POST"`) {
+			t.Fatalf("want echo response from %s, got: %s", image, out)
+		}
+
+		if !pulledWith(reg, tag, true) {
+			t.Fatalf("the function started but %s was never pulled with credentials", image)
+		}
+	})
+}
+
+// loadPrivateImage stores the echo fixture from the fixtures build output in
+// the registry stand-in.
+func loadPrivateImage(t *testing.T, reg *registrytest.Registry, tag string) {
+	t.Helper()
+
+	path := filepath.Join(config.FixturesDir, "echo.tar")
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unable to open the echo fixture, run `make fixtures` first: %s", err)
+	}
+	defer f.Close()
+
+	layout, err := oci.ReadLayout(f)
+	if err != nil {
+		t.Fatalf("unable to read %s: %s", path, err)
+	}
+
+	if len(layout.Index.Manifests) == 0 {
+		t.Fatalf("%s does not contain an image", path)
+	}
+
+	if err := reg.AddImage(privateRepository, tag, layout, layout.Index.Manifests[0]); err != nil {
+		t.Fatalf("unable to load %s into the registry stand-in: %s", path, err)
+	}
+}
+
+func pulledWith(reg *registrytest.Registry, tag string, authorized bool) bool {
+	for _, pull := range reg.Pulls() {
+		if pull.Repository == privateRepository && pull.Authorized == authorized {
+			if authorized || pull.Reference == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runPullSecretCommand runs the provider specific script that installs or
+// removes the registry credentials, see contrib/pull_secret_*.sh
+func runPullSecretCommand(t *testing.T, action string, env []string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, config.PullSecretCommand, action)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s %s failed: %s\n%s", config.PullSecretCommand, action, err, out)
+	}
+}
@@ -0,0 +1,285 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/openfaas/certifier/internal/images"
+)
+
+// Profile declares provider behaviour that the OpenFaaS API leaves open,
+// so that the checks can assert the documented behaviour instead of
+// guessing. Profiles are JSON files, the certifier ships one per known
+// provider in the profiles folder.
+type Profile struct {
+	// ImageComparison is "strict" when the provider reports the image exactly
+	// as deployed or "normalized" when it applies the registry and tag
+	// defaults, e.g. docker.io/functions/alpine:latest
+	ImageComparison string `json:"imageComparison"`
+
+	// ScaleAboveMax is what /system/scale-function does with a replica count
+	// above com.openfaas.scale.max: "reject" it with an error status, "clamp"
+	// it to the maximum or "allow" it for a provider that leaves the limit to
+	// the autoscaler and treats a manual scale as an override
+	ScaleAboveMax string `json:"scaleAboveMax"`
+
+	// RollingUpdate is how an update replaces running replicas: "atomic" when
+	// responses switch from the old to the new version exactly once or
+	// "overlapping" when old and new replicas serve side by side until the
+	// update converges
+	RollingUpdate string `json:"rollingUpdate"`
+
+	// HealthAnnotations is true when the provider implements the
+	// com.openfaas.health.* and com.openfaas.ready.* annotations
+	HealthAnnotations bool `json:"healthAnnotations"`
+
+	// Orchestration is the orchestration reported by /system/info when it is
+	// not one of the known values, e.g. "kubernetes" or "containerd"
+	Orchestration string `json:"orchestration"`
+
+	// Limits are the payload size limits the provider enforces
+	Limits LimitsProfile `json:"limits"`
+
+	// Labels declares how the provider handles invalid labels
+	Labels LabelsProfile `json:"labels"`
+
+	// Secrets declares the secret limits and how a missing secret is handled
+	Secrets SecretsProfile `json:"secrets"`
+
+	// Autoscaling describes the policies of the provider's autoscaler
+	Autoscaling AutoscalingProfile `json:"autoscaling"`
+}
+
+// AutoscalingProfile declares which com.openfaas.scale.* policies the
+// autoscaler implements and how quickly it scales back down.
+type AutoscalingProfile struct {
+	// Policies lists the supported policies: "factor" for the alert based
+	// scaling driven by com.openfaas.scale.factor and the
+	// com.openfaas.scale.type values "rps", "capacity" and "cpu"
+	Policies []string `json:"policies"`
+
+	// ScaleDownWindow is how long the autoscaler keeps the replicas after the
+	// load stops before it scales down, e.g. "2m"
+	ScaleDownWindow Duration `json:"scaleDownWindow"`
+}
+
+// LabelsProfile declares what happens to labels that are not valid
+// Kubernetes label keys or values.
+type LabelsProfile struct {
+	// Validation is "reject" when a deployment with invalid labels is
+	// rejected with a 4xx status or "passthrough" when it is accepted and the
+	// labels are reported as submitted
+	Validation string `json:"validation"`
+}
+
+// LimitsProfile declares size limits, 0 means no limit. Payloads above a
+// limit must be rejected with 413 Request Entity Too Large.
+type LimitsProfile struct {
+	// RequestBytes is the largest invocation request body
+	RequestBytes int64 `json:"requestBytes"`
+	// ResponseBytes is the largest invocation response body
+	ResponseBytes int64 `json:"responseBytes"`
+}
+
+// SecretsProfile declares the limits of secrets and what happens to a
+// function that references a secret that does not exist.
+type SecretsProfile struct {
+	// MaxBytes is the largest secret value, 0 means no declared limit.
+	// Larger values must be rejected with a 4xx status.
+	MaxBytes int64 `json:"maxBytes"`
+	// NameMaxLength is the longest secret name the provider accepts
+	NameMaxLength int `json:"nameMaxLength"`
+	// Missing is "reject" when the deployment is rejected with a 4xx status
+	// or "unavailable" when it is accepted but never becomes available
+	Missing string `json:"missing"`
+	// DeleteInUse is what removing a secret that is still mounted does:
+	// "refuse" it with an error status, "allow" it while the function keeps
+	// serving the last value or "break" the function, which then fails or
+	// loses the secret
+	DeleteInUse string `json:"deleteInUse"`
+	// HotUpdate is true when an updated secret reaches the running replicas
+	// without a redeploy
+	HotUpdate bool `json:"hotUpdate"`
+	// HotUpdateWindow is how long an update may take to reach the running
+	// replicas, e.g. "2m"
+	HotUpdateWindow Duration `json:"hotUpdateWindow"`
+}
+
+// Supports reports whether the autoscaler implements policy.
+func (a AutoscalingProfile) Supports(policy string) bool {
+	for _, p := range a.Policies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// Duration is a time.Duration written as a string in JSON, e.g. "90s".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+const (
+	scaleAboveMaxReject = "reject"
+	scaleAboveMaxClamp  = "clamp"
+	scaleAboveMaxAllow  = "allow"
+)
+
+const (
+	rollingUpdateAtomic      = "atomic"
+	rollingUpdateOverlapping = "overlapping"
+)
+
+const (
+	labelValidationReject      = "reject"
+	labelValidationPassthrough = "passthrough"
+)
+
+const (
+	missingSecretReject      = "reject"
+	missingSecretUnavailable = "unavailable"
+)
+
+const (
+	deleteInUseRefuse = "refuse"
+	deleteInUseAllow  = "allow"
+	deleteInUseBreak  = "break"
+)
+
+const (
+	scalePolicyFactor   = "factor"
+	scalePolicyRPS      = "rps"
+	scalePolicyCapacity = "capacity"
+	scalePolicyCPU      = "cpu"
+)
+
+// defaultProfile is used for providers without a profile file
+var defaultProfile = Profile{
+	ImageComparison:   images.CompareStrict,
+	ScaleAboveMax:     scaleAboveMaxReject,
+	RollingUpdate:     rollingUpdateAtomic,
+	HealthAnnotations: true,
+	Labels: LabelsProfile{
+		Validation: labelValidationReject,
+	},
+	Secrets: SecretsProfile{
+		// the length of a DNS subdomain, which Kubernetes uses for names
+		NameMaxLength: 253,
+		Missing:       missingSecretReject,
+		DeleteInUse:   deleteInUseAllow,
+	},
+	Autoscaling: AutoscalingProfile{
+		Policies: []string{scalePolicyFactor},
+	},
+}
+
+// loadProfile reads the profile file, falling back to the file shipped for
+// the provider in profilesDir and finally to the default profile.
+func loadProfile(path, profilesDir, provider string) (Profile, error) {
+	profile := defaultProfile
+
+	if path == "" {
+		candidate := filepath.Join(profilesDir, provider+".json")
+		if _, err := os.Stat(candidate); err == nil {
+			path = candidate
+		}
+	}
+
+	if path == "" {
+		return profile, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return profile, fmt.Errorf("unable to read profile: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return profile, fmt.Errorf("unable to parse profile %s: %w", path, err)
+	}
+
+	switch profile.ImageComparison {
+	case images.CompareStrict, images.CompareNormalized:
+	default:
+		return profile, fmt.Errorf("profile %s: imageComparison must be %q or %q, got %q",
+			path, images.CompareStrict, images.CompareNormalized, profile.ImageComparison)
+	}
+
+	switch profile.ScaleAboveMax {
+	case scaleAboveMaxReject, scaleAboveMaxClamp, scaleAboveMaxAllow:
+	default:
+		return profile, fmt.Errorf("profile %s: scaleAboveMax must be %q, %q or %q, got %q",
+			path, scaleAboveMaxReject, scaleAboveMaxClamp, scaleAboveMaxAllow, profile.ScaleAboveMax)
+	}
+
+	switch profile.RollingUpdate {
+	case rollingUpdateAtomic, rollingUpdateOverlapping:
+	default:
+		return profile, fmt.Errorf("profile %s: rollingUpdate must be %q or %q, got %q",
+			path, rollingUpdateAtomic, rollingUpdateOverlapping, profile.RollingUpdate)
+	}
+
+	switch profile.Labels.Validation {
+	case labelValidationReject, labelValidationPassthrough:
+	default:
+		return profile, fmt.Errorf("profile %s: labels.validation must be %q or %q, got %q",
+			path, labelValidationReject, labelValidationPassthrough, profile.Labels.Validation)
+	}
+
+	switch profile.Secrets.Missing {
+	case missingSecretReject, missingSecretUnavailable:
+	default:
+		return profile, fmt.Errorf("profile %s: secrets.missing must be %q or %q, got %q",
+			path, missingSecretReject, missingSecretUnavailable, profile.Secrets.Missing)
+	}
+
+	switch profile.Secrets.DeleteInUse {
+	case deleteInUseRefuse, deleteInUseAllow, deleteInUseBreak:
+	default:
+		return profile, fmt.Errorf("profile %s: secrets.deleteInUse must be %q, %q or %q, got %q",
+			path, deleteInUseRefuse, deleteInUseAllow, deleteInUseBreak, profile.Secrets.DeleteInUse)
+	}
+
+	if profile.Secrets.HotUpdate && profile.Secrets.HotUpdateWindow.Duration <= 0 {
+		return profile, fmt.Errorf("profile %s: secrets.hotUpdateWindow is required with secrets.hotUpdate", path)
+	}
+
+	if profile.Secrets.NameMaxLength <= 0 {
+		return profile, fmt.Errorf("profile %s: secrets.nameMaxLength must be positive, got %d",
+			path, profile.Secrets.NameMaxLength)
+	}
+
+	for _, policy := range profile.Autoscaling.Policies {
+		switch policy {
+		case scalePolicyFactor, scalePolicyRPS, scalePolicyCapacity, scalePolicyCPU:
+		default:
+			return profile, fmt.Errorf("profile %s: unknown autoscaling policy %q", path, policy)
+		}
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+)
+
+// Report collects the measurements taken during a run, e.g. latencies, so
+// they can be compared between providers and releases. It is written as JSON
+// to the -report file when the run completes.
+type Report struct {
+	mu sync.Mutex
+
+	Provider string `json:"provider"`
+	Gateway  string `json:"gateway"`
+	// Combination is the provider and gateway release under test, e.g.
+	// "provider faas-netes v0.13.4 with gateway v0.20.11"
+	Combination string `json:"combination,omitempty"`
+	// Certified repeats Combination when every check of a run without a -run
+	// filter passed, the checks that were skipped are listed in Skipped
+	Certified string `json:"certified,omitempty"`
+	// Run is the -run filter of a partial run, which is never certified
+	Run string `json:"run,omitempty"`
+	// Skipped maps the skipped tests to the reason
+	Skipped map[string]string `json:"skipped,omitempty"`
+	// Results maps the test name to its named measurements
+	Results map[string]map[string]interface{} `json:"results"`
+}
+
+// report is the report of the current run
+var report = &Report{Results: map[string]map[string]interface{}{}}
+
+// Add records a measurement for the test, replacing an earlier value with
+// the same key.
+func (r *Report) Add(test, key string, value interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Results[test] == nil {
+		r.Results[test] = map[string]interface{}{}
+	}
+	r.Results[test][key] = value
+}
+
+// Skip records that the test was skipped and why.
+func (r *Report) Skip(test, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Skipped == nil {
+		r.Skipped = map[string]string{}
+	}
+	r.Skipped[test] = reason
+}
+
+// WriteFile writes the report as indented JSON.
+func (r *Report) WriteFile(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,218 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	echo "github.com/openfaas/certifier/functions/echo"
+	sdk "github.com/openfaas/faas-cli/proxy"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+const (
+	// rollingUpdateWorkers is the number of clients invoking the function
+	// in a loop during the update
+	rollingUpdateWorkers = 4
+	// rollingUpdateGrace is how long old replicas may still answer once the
+	// update has converged, for providers with overlapping updates
+	rollingUpdateGrace = 10 * time.Second
+)
+
+// observation is one invocation made during the rolling update.
+type observation struct {
+	// client is the worker that made the invocation
+	client   int
+	start    time.Time
+	end      time.Time
+	status   int
+	revision string
+	err      error
+}
+
+func Test_RollingUpdateUnderLoad(t *testing.T) {
+	if !config.EnableScaling {
+		skipf(t, "rolling updates with scale.min=2 are not supported for %s", config.ProviderName)
+	}
+
+	functionName := "test-rolling-update"
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "echo"),
+		FunctionName: functionName,
+		Network:      "func_functions",
+		EnvVars:      map[string]string{"revision": "1"},
+		Labels:       map[string]string{"com.openfaas.scale.min": "2"},
+		Namespace:    config.DefaultNamespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err := waitForFunctionStatus(time.Minute, functionName, config.DefaultNamespace, minAvailableReplicaCount(2))
+	if err != nil {
+		t.Fatalf("Function %q failed to start 2 replicas: %s", functionName, err)
+	}
+
+	uri := resourceURL(t, path.Join("function", fmt.Sprintf("%s.%s", functionName, functionRequest.Namespace)), "")
+
+	var (
+		mu           sync.Mutex
+		observations []observation
+		wg           sync.WaitGroup
+	)
+
+	stop := make(chan struct{})
+	for i := 0; i < rollingUpdateWorkers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			client := http.Client{Timeout: 10 * time.Second}
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				o := invokeRevision(&client, uri)
+				o.client = worker
+				mu.Lock()
+				observations = append(observations, o)
+				mu.Unlock()
+			}
+		}(i)
+	}
+
+	// a baseline of responses from the running version
+	time.Sleep(5 * time.Second)
+
+	updated := time.Now()
+	functionRequest.Update = true
+	functionRequest.EnvVars = map[string]string{"revision": "2"}
+	deployStatus = deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		close(stop)
+		wg.Wait()
+		t.Fatalf("update got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+
+	err = waitForFunctionStatus(config.ScaleTimeout, functionName, config.DefaultNamespace, func(fnc types.FunctionStatus) bool {
+		return fnc.EnvVars["revision"] == "2" && fnc.AvailableReplicas >= 2
+	})
+	converged := time.Now()
+
+	// keep the load running past the grace period, old replicas must be gone
+	// by then
+	time.Sleep(2 * rollingUpdateGrace)
+	close(stop)
+	wg.Wait()
+
+	if err != nil {
+		t.Fatalf("update did not converge within %s: %s", config.ScaleTimeout, err)
+	}
+	t.Logf("update converged after %s", converged.Sub(updated).Round(time.Second))
+
+	counts := map[string]int{}
+	var failures []observation
+	var firstNew time.Time
+	for _, o := range observations {
+		switch {
+		case o.err != nil:
+			counts["error"]++
+			failures = append(failures, o)
+		case o.status >= 500:
+			counts[fmt.Sprint(o.status)]++
+			failures = append(failures, o)
+		default:
+			counts["revision "+o.revision]++
+			if o.revision == "2" && (firstNew.IsZero() || o.end.Before(firstNew)) {
+				firstNew = o.end
+			}
+		}
+	}
+
+	report.Add(t.Name(), "responses", counts)
+	t.Logf("%d invocations: %v", len(observations), counts)
+
+	for i, o := range failures {
+		if i == 5 {
+			t.Logf("... %d more", len(failures)-i)
+			break
+		}
+		t.Logf("failed %s after the update: status %d, error %v", o.start.Sub(updated).Round(time.Millisecond), o.status, o.err)
+	}
+	if len(failures) > 0 {
+		t.Fatalf("%d of %d invocations failed during the rolling update", len(failures), len(observations))
+	}
+
+	if counts["revision 1"] == 0 || counts["revision 2"] == 0 {
+		t.Fatalf("want responses from both revisions, got %v", counts)
+	}
+
+	// an old response to a request sent after the switch goes back to the
+	// previous version
+	switchedAt := firstNew
+	if config.Profile.RollingUpdate == rollingUpdateOverlapping {
+		switchedAt = converged.Add(rollingUpdateGrace)
+	}
+
+	// observations are appended in order by each client, a client that got
+	// the new version once must not get the old one after the update has
+	// converged, even while old replicas are still draining
+	seenNew := map[int]bool{}
+	for _, o := range observations {
+		if o.revision == "2" {
+			seenNew[o.client] = true
+		}
+		if o.revision == "1" && seenNew[o.client] && o.start.After(converged) {
+			t.Fatalf("client %d got revision 1 for a request sent %s after the update, after it got revision 2",
+				o.client, o.start.Sub(updated).Round(time.Millisecond))
+		}
+		if o.revision == "1" && o.start.After(switchedAt) {
+			t.Fatalf("got revision 1 for a request sent %s after the update, responses went back to the old version",
+				o.start.Sub(updated).Round(time.Millisecond))
+		}
+		if o.revision != "1" && o.revision != "2" && o.err == nil && o.status < 500 {
+			t.Fatalf("unexpected revision %q with status %d", o.revision, o.status)
+		}
+	}
+}
+
+// invokeRevision invokes the echo fixture once and returns the revision env
+// variable it responded with.
+func invokeRevision(client *http.Client, uri string) observation {
+	o := observation{start: time.Now()}
+
+	res, err := client.Post(uri, "text/plain", nil)
+	if err != nil {
+		o.err = err
+		o.end = time.Now()
+		return o
+	}
+	defer res.Body.Close()
+
+	o.status = res.StatusCode
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		o.err = err
+		o.end = time.Now()
+		return o
+	}
+
+	var echoed echo.Response
+	if res.StatusCode == http.StatusOK && json.Unmarshal(body, &echoed) == nil {
+		o.revision = echoed.Env["revision"]
+	}
+
+	o.end = time.Now()
+	return o
+}
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+func Test_ScaleFunction(t *testing.T) {
+	if !config.EnableScaling {
+		skipf(t, "scale function is not supported for %s", config.ProviderName)
+	}
+
+	functionName := "test-scale-function"
+	maxReplicas := uint64(3)
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "hash"),
+		FunctionName: functionName,
+		Network:      "func_functions",
+		EnvVars:      map[string]string{"revision": "1"},
+		Labels: map[string]string{
+			"com.openfaas.scale.min": "1",
+			"com.openfaas.scale.max": fmt.Sprintf("%d", maxReplicas),
+		},
+		Namespace: config.DefaultNamespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err := waitForFunctionStatus(time.Minute, functionName, config.DefaultNamespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", functionName, err)
+	}
+
+	for _, replicas := range []uint64{0, 1, 3, 1} {
+		t.Run(fmt.Sprintf("scale to %d", replicas), func(t *testing.T) {
+			scaleAndConverge(t, functionName, config.DefaultNamespace, replicas)
+		})
+	}
+
+	t.Run("scale above com.openfaas.scale.max", func(t *testing.T) {
+		requested := maxReplicas + 2
+		statusCode, body := scaleRequest(t, functionName, config.DefaultNamespace, requested)
+		fnc := get(t, functionName, config.DefaultNamespace)
+
+		switch config.Profile.ScaleAboveMax {
+		case scaleAboveMaxReject:
+			if statusCode < 400 {
+				t.Fatalf("want scaling to %d above max %d rejected, got %d", requested, maxReplicas, statusCode)
+			}
+			if fnc.Replicas != 1 {
+				t.Fatalf("rejected scale request changed replicas to %d, wanted 1", fnc.Replicas)
+			}
+		case scaleAboveMaxClamp:
+			if !isSuccess(statusCode) {
+				t.Fatalf("want scaling above max clamped, got %d: %s", statusCode, body)
+			}
+			if fnc.Replicas != maxReplicas {
+				t.Fatalf("got %d replicas, wanted the request clamped to %d", fnc.Replicas, maxReplicas)
+			}
+		default:
+			if !isSuccess(statusCode) {
+				t.Fatalf("want scaling above max allowed, got %d: %s", statusCode, body)
+			}
+			if fnc.Replicas != requested {
+				t.Fatalf("got %d replicas, wanted %d", fnc.Replicas, requested)
+			}
+		}
+
+		scaleAndConverge(t, functionName, config.DefaultNamespace, 1)
+	})
+
+	t.Run("update keeps the manual replica count", func(t *testing.T) {
+		scaleAndConverge(t, functionName, config.DefaultNamespace, 2)
+
+		functionRequest.Update = true
+		functionRequest.EnvVars = map[string]string{"revision": "2"}
+		deployStatus := deploy(t, functionRequest)
+		if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+			t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+		}
+
+		// give the provider time to act on the update before checking it did
+		// not reset the replicas
+		kept := statusNeverReached(t, 15*time.Second, functionName, config.DefaultNamespace, func(fnc types.FunctionStatus) bool {
+			return fnc.Replicas != 2
+		})
+		if !kept {
+			fnc := get(t, functionName, config.DefaultNamespace)
+			t.Fatalf("update changed replicas to %d, wanted 2", fnc.Replicas)
+		}
+
+		fnc := get(t, functionName, config.DefaultNamespace)
+		if fnc.EnvVars["revision"] != "2" {
+			t.Fatalf("update was not applied, got env %v", fnc.EnvVars)
+		}
+	})
+
+	t.Run("scale unknown function", func(t *testing.T) {
+		statusCode, body := scaleRequest(t, "test-scale-function-unknown", config.DefaultNamespace, 1)
+		if statusCode != http.StatusNotFound {
+			t.Fatalf("got %d, wanted %d: %s", statusCode, http.StatusNotFound, body)
+		}
+	})
+}
+
+// scaleAndConverge scales the function, checks the requested replicas are
+// reported immediately and waits for the available replicas to match.
+func scaleAndConverge(t *testing.T, name, namespace string, replicas uint64) {
+	t.Helper()
+
+	err := config.Client.ScaleFunction(context.Background(), name, namespace, replicas)
+	if err != nil {
+		t.Fatalf("scaling %s to %d failed: %s", name, replicas, err)
+	}
+
+	fnc := get(t, name, namespace)
+	if fnc.Replicas != replicas {
+		t.Fatalf("got %d replicas right after scaling, wanted %d", fnc.Replicas, replicas)
+	}
+
+	start := time.Now()
+	err = waitForFunctionStatus(config.ScaleTimeout, name, namespace, availableReplicaCount(replicas))
+	if err != nil {
+		fnc = get(t, name, namespace)
+		t.Fatalf("available replicas did not converge to %d within %s, got %d: %s",
+			replicas, config.ScaleTimeout, fnc.AvailableReplicas, err)
+	}
+	t.Logf("%d available replicas after %s", replicas, time.Since(start).Round(time.Second))
+}
+
+// scaleRequest calls /system/scale-function directly so the status code can
+// be asserted, the SDK maps it to an error message.
+func scaleRequest(t *testing.T, name, namespace string, replicas uint64) (int, string) {
+	t.Helper()
+
+	body, err := json.Marshal(types.ScaleServiceRequest{ServiceName: name, Replicas: replicas})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := resourceURL(t, path.Join("system", "scale-function", name), "namespace="+namespace)
+	out, res := request(t, uri, http.MethodPost, config.Auth, strings.NewReader(string(body)))
+	return res.StatusCode, string(out)
+}
+
+func isSuccess(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
@@ -1,16 +1,17 @@
 package tests
 
 import (
-	"bytes"
-	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"os"
 	"path"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/openfaas/certifier/internal/stats"
 	sdk "github.com/openfaas/faas-cli/proxy"
 	types "github.com/openfaas/faas-provider/types"
 	"github.com/rakyll/hey/requester"
@@ -18,7 +19,7 @@ import (
 
 func Test_ScaleMinimum(t *testing.T) {
 	if !config.EnableScaling {
-		t.Skipf("scale to minimum is not supported for %s", config.ProviderName)
+		skipf(t, "scale to minimum is not supported for %s", config.ProviderName)
 	}
 	functionName := "test-min-scale"
 	minReplicas := uint64(2)
@@ -26,10 +27,9 @@ func Test_ScaleMinimum(t *testing.T) {
 		"com.openfaas.scale.min": fmt.Sprintf("%d", minReplicas),
 	}
 	functionRequest := &sdk.DeployFunctionSpec{
-		Image:        "functions/alpine:latest",
+		Image:        fixtureImage(t, "hash"),
 		FunctionName: functionName,
 		Network:      "func_functions",
-		FProcess:     "sha512sum",
 		Labels:       labels,
 		Namespace:    config.DefaultNamespace,
 	}
@@ -49,14 +49,14 @@ func Test_ScaleMinimum(t *testing.T) {
 
 func Test_ScaleFromZeroDuringInvoke(t *testing.T) {
 	if !config.EnableScaling {
-		t.Skipf("scale to zero is not supported for %s", config.ProviderName)
+		skipf(t, "scale to zero is not supported for %s", config.ProviderName)
 	}
+	requireGateway(t, "scale from zero during invoke")
 	functionName := "test-scale-from-zero"
 	functionRequest := &sdk.DeployFunctionSpec{
-		Image:        "functions/alpine:latest",
+		Image:        fixtureImage(t, "hash"),
 		FunctionName: functionName,
 		Network:      "func_functions",
-		FProcess:     "sha512sum",
 		Namespace:    config.DefaultNamespace,
 	}
 
@@ -71,24 +71,67 @@ func Test_ScaleFromZeroDuringInvoke(t *testing.T) {
 		t.Fatalf("Function %q failed to start: %s", functionName, err)
 	}
 
-	err = config.Client.ScaleFunction(context.Background(), functionName, config.DefaultNamespace, 0)
+	var latencies []time.Duration
+	for i := 0; i < config.ColdStartCycles; i++ {
+		scaleAndConverge(t, functionName, config.DefaultNamespace, 0)
+
+		latency := coldStart(t, functionRequest)
+		t.Logf("[%d/%d] cold start time to first byte %s", i+1, config.ColdStartCycles, latency)
+		latencies = append(latencies, latency)
+	}
+
+	summary := stats.Summarize(latencies)
+	report.Add(t.Name(), "coldStart", summary)
+	t.Logf("cold start over %d cycles: p50 %s, p95 %s", summary.Count, summary.P50, summary.P95)
+
+	if config.ColdStartSLO > 0 && summary.P95 > config.ColdStartSLO {
+		t.Fatalf("p95 cold start latency %s is above the %s SLO", summary.P95, config.ColdStartSLO)
+	}
+}
+
+// coldStart invokes a function scaled to zero exactly once, the gateway must
+// hold the request until a replica is ready rather than rely on the client
+// retrying. It returns the time to the first byte of the response.
+func coldStart(t *testing.T, function *sdk.DeployFunctionSpec) time.Duration {
+	t.Helper()
+
+	uri := resourceURL(t, path.Join("function", fmt.Sprintf("%s.%s", function.FunctionName, function.Namespace)), "")
+	req, err := http.NewRequest(http.MethodPost, uri, nil)
 	if err != nil {
-		t.Fatalf("Scaling down function to zero failed: %s", err)
+		t.Fatalf("error with request %s ", err)
 	}
 
-	fnc := get(t, functionName, config.DefaultNamespace)
-	if fnc.Replicas != 0 {
-		t.Fatalf("got %d replicas, wanted %d", fnc.Replicas, 0)
+	var firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	client := http.Client{Timeout: config.ScaleTimeout}
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("cold start invoke failed: %s", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("error reading response body %s ", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("cold start invoke got %d, wanted %d without retries: %s", res.StatusCode, http.StatusOK, body)
 	}
 
-	// this will fail or pass the test
-	_ = invoke(t, functionRequest, "", "", http.StatusOK)
+	return firstByte.Sub(start)
 }
 
 func Test_ScaleUpAndDownFromThroughPut(t *testing.T) {
 	if !config.EnableScaling {
-		t.Skipf("scale up and down is not supported for %s", config.ProviderName)
+		skipf(t, "scale up and down is not supported for %s", config.ProviderName)
 	}
+	requireGateway(t, "autoscaling")
 	functionName := "test-throughput-scaling"
 	minReplicas := uint64(1)
 	maxReplicas := uint64(2)
@@ -97,10 +140,9 @@ func Test_ScaleUpAndDownFromThroughPut(t *testing.T) {
 		"com.openfaas.scale.max": fmt.Sprintf("%d", maxReplicas),
 	}
 	functionRequest := &sdk.DeployFunctionSpec{
-		Image:        "functions/alpine:latest",
+		Image:        fixtureImage(t, "hash"),
 		FunctionName: functionName,
 		Network:      "func_functions",
-		FProcess:     "sha512sum",
 		Labels:       labels,
 		Namespace:    config.DefaultNamespace,
 	}
@@ -112,41 +154,24 @@ func Test_ScaleUpAndDownFromThroughPut(t *testing.T) {
 
 	defer deleteFunction(t, functionRequest)
 
-	functionURL := resourceURL(t, path.Join("function", functionName), "")
-	req, err := http.NewRequest(http.MethodPost, functionURL, nil)
-	if err != nil {
-		t.Fatalf("error with request %s ", err)
-	}
-
-	var loadOutput bytes.Buffer
 	attempts := 1000
-	functionLoad := requester.Work{
-		Request:           req,
+	functionLoad := startLoad(t, functionRequest, &requester.Work{
 		N:                 attempts,
 		Timeout:           10,
 		C:                 2,
 		QPS:               5.0,
 		DisableKeepAlives: true,
-		Writer:            &loadOutput,
-	}
-
-	functionLoad.Init()
-	go func() {
-		functionLoad.Run()
-	}()
+	})
 
 	var status types.FunctionStatus
 	_ = waitForFunctionStatus(time.Minute, functionName, config.DefaultNamespace, func(fnc types.FunctionStatus) bool {
 		status = fnc
-		if fnc.Replicas >= maxReplicas {
-			functionLoad.Stop()
-			return true
-		}
-		return false
+		return fnc.Replicas >= maxReplicas
 	})
+	result := functionLoad.Stop(t)
 
 	if status.Replicas != maxReplicas {
-		t.Logf("function load output %s", loadOutput.String())
+		t.Logf("function load %s", result)
 		t.Fatalf("never reached max scale %d, only %d replicas after %d attempts", maxReplicas, status.Replicas, attempts)
 	}
 
@@ -160,8 +185,9 @@ func Test_ScaleUpAndDownFromThroughPut(t *testing.T) {
 
 func Test_ScalingDisabledViaLabels(t *testing.T) {
 	if !config.EnableScaling {
-		t.Skipf("scaling disabled via label is not supported for %s", config.ProviderName)
+		skipf(t, "scaling disabled via label is not supported for %s", config.ProviderName)
 	}
+	requireGateway(t, "autoscaling")
 	functionName := "test-scaling-disabled"
 	minReplicas := uint64(2)
 	maxReplicas := minReplicas
@@ -173,10 +199,9 @@ func Test_ScalingDisabledViaLabels(t *testing.T) {
 		"com.openfaas.scale.max": fmt.Sprintf("%d", maxReplicas),
 	}
 	functionRequest := &sdk.DeployFunctionSpec{
-		Image:        "functions/alpine:latest",
+		Image:        fixtureImage(t, "hash"),
 		FunctionName: functionName,
 		Network:      "func_functions",
-		FProcess:     "sha512sum",
 		Labels:       labels,
 		Namespace:    config.DefaultNamespace,
 	}
@@ -188,38 +213,29 @@ func Test_ScalingDisabledViaLabels(t *testing.T) {
 
 	defer deleteFunction(t, functionRequest)
 
-	functionURL := resourceURL(t, path.Join("function", functionName), "")
-	req, err := http.NewRequest(http.MethodPost, functionURL, nil)
-	if err != nil {
-		t.Fatalf("error with request %s ", err)
-	}
-
-	var loadOutput bytes.Buffer
 	attempts := 1000
-	functionLoad := requester.Work{
-		Request:           req,
+	functionLoad := startLoad(t, functionRequest, &requester.Work{
 		N:                 attempts,
 		Timeout:           10,
 		C:                 2,
 		QPS:               5.0,
 		DisableKeepAlives: true,
-		Writer:            &loadOutput,
-	}
+	})
 
-	functionLoad.Init()
-	functionLoad.Run()
+	result := functionLoad.Wait(t)
 
 	fnc := get(t, functionName, config.DefaultNamespace)
 	if fnc.Replicas != minReplicas {
-		t.Logf("function load output %s", loadOutput.String())
+		t.Logf("function load %s", result)
 		t.Fatalf("unexpected scaling, expected %d, got %d replicas after %d attempts", minReplicas, fnc.Replicas, attempts)
 	}
 }
 
 func Test_ScaleToZero(t *testing.T) {
 	if !config.EnableScaling {
-		t.Skipf("scale to zero is not supported for %s", config.ProviderName)
+		skipf(t, "scale to zero is not supported for %s", config.ProviderName)
 	}
+	requireGateway(t, "scale to zero by the idler")
 
 	idlerEnabled := os.Getenv("idler_enabled")
 	if idlerEnabled == "" {
@@ -232,7 +248,7 @@ func Test_ScaleToZero(t *testing.T) {
 	}
 
 	if !enableTest {
-		t.Skip("set 'idler_enabled' to test scale to zero")
+		skipf(t, "set 'idler_enabled' to test scale to zero")
 	}
 
 	functionName := "test-scaling-to-zero"
@@ -242,10 +258,9 @@ func Test_ScaleToZero(t *testing.T) {
 		"com.openfaas.scale.zero": "true",
 	}
 	functionRequest := &sdk.DeployFunctionSpec{
-		Image:        "functions/alpine:latest",
+		Image:        fixtureImage(t, "hash"),
 		FunctionName: functionName,
 		Network:      "func_functions",
-		FProcess:     "sha512sum",
 		Labels:       labels,
 		Namespace:    config.DefaultNamespace,
 	}
@@ -257,30 +272,20 @@ func Test_ScaleToZero(t *testing.T) {
 
 	defer deleteFunction(t, functionRequest)
 
-	functionURL := resourceURL(t, path.Join("function", functionName), "")
-	req, err := http.NewRequest(http.MethodPost, functionURL, nil)
-	if err != nil {
-		t.Fatalf("error with request %s ", err)
-	}
-
-	var loadOutput bytes.Buffer
 	attempts := 1000
-	functionLoad := requester.Work{
-		Request:           req,
+	functionLoad := startLoad(t, functionRequest, &requester.Work{
 		N:                 attempts,
 		Timeout:           10,
 		C:                 2,
 		QPS:               5.0,
 		DisableKeepAlives: true,
-		Writer:            &loadOutput,
-	}
+	})
 
-	functionLoad.Init()
-	functionLoad.Run()
+	result := functionLoad.Wait(t)
 
 	fnc := get(t, functionName, config.DefaultNamespace)
 	if fnc.Replicas != maxReplicas {
-		t.Logf("function load output %s", loadOutput.String())
+		t.Logf("function load %s", result)
 		t.Fatalf("never reached max scale %d, only %d replicas after %d attempts", maxReplicas, fnc.Replicas, attempts)
 	}
 
@@ -2,11 +2,13 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
 	"time"
 
+	secrets "github.com/openfaas/certifier/functions/secrets"
 	sdk "github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-provider/types"
 )
@@ -67,10 +69,6 @@ func Test_SecretCRUD(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			functionName := tc.secret.Name
-			value := tc.secret.Value
-			if tc.secret.Value == "" {
-				value = string(tc.secret.RawValue)
-			}
 
 			// Verify that the secret are empty.
 			secrets, err := config.Client.GetSecretList(ctx, tc.secret.Namespace)
@@ -84,12 +82,11 @@ func Test_SecretCRUD(t *testing.T) {
 
 			t.Logf("Secrets found in %s: %v", tc.secret.Namespace, secrets)
 
-			// Set up and deploy function that reads the value of the created secret.
+			// Set up and deploy function that hashes the value of the created secret.
 			functionRequest := &sdk.DeployFunctionSpec{
-				Image:        "functions/alpine:latest",
+				Image:        fixtureImage(t, "secrets"),
 				FunctionName: functionName,
 				Network:      "func_functions",
-				FProcess:     "cat /var/openfaas/secrets/" + tc.secret.Name,
 				Secrets:      []string{tc.secret.Name},
 				Namespace:    tc.secret.Namespace,
 				Annotations:  map[string]string{},
@@ -113,9 +110,9 @@ func Test_SecretCRUD(t *testing.T) {
 				}
 
 				// Verify that the secret value was set as intended.
-				mountedValue := string(invoke(t, functionRequest, "", "", http.StatusOK))
-				if mountedValue != value {
-					t.Errorf("got %s, wanted %s", mountedValue, value)
+				mounted := mountedSecret(t, functionRequest, tc.secret.Name)
+				if want := wantSecret(tc.secret); mounted != want {
+					t.Errorf("got %+v, wanted %+v", mounted, want)
 				}
 			})
 
@@ -134,15 +131,10 @@ func Test_SecretCRUD(t *testing.T) {
 			t.Run("update", func(t *testing.T) {
 				if !config.SecretUpdate {
 					// Docker Swarm secrets are immutable, so skip the update tests for swarm.
-					t.Skip("secret update not enabled")
+					skipf(t, "secret update not enabled")
 					return
 				}
 
-				value := tc.secretUpdate.Value
-				if tc.secretUpdate.Value == "" {
-					value = string(tc.secretUpdate.RawValue)
-				}
-
 				updateStatus, _ := config.Client.UpdateSecret(ctx, tc.secretUpdate)
 				if updateStatus != http.StatusOK && updateStatus != http.StatusAccepted {
 					t.Errorf("got %d, wanted %d or %d", updateStatus, http.StatusOK, http.StatusAccepted)
@@ -162,9 +154,9 @@ func Test_SecretCRUD(t *testing.T) {
 				time.Sleep(5 * time.Second)
 
 				// Verify that the secret value was updated and mounted
-				mountedValue := string(invoke(t, functionRequest, "", "", http.StatusOK))
-				if mountedValue != value {
-					t.Errorf("got %s, wanted %s", mountedValue, value)
+				mounted := mountedSecret(t, functionRequest, tc.secret.Name)
+				if want := wantSecret(tc.secretUpdate); mounted != want {
+					t.Errorf("got %+v, wanted %+v", mounted, want)
 				}
 			})
 
@@ -203,3 +195,22 @@ func listContains(list []types.Secret, s string) bool {
 	}
 	return false
 }
+
+// mountedSecret invokes the secrets fixture and returns what it reports for
+// the secret name.
+func mountedSecret(t *testing.T, function *sdk.DeployFunctionSpec, name string) secrets.Secret {
+	t.Helper()
+
+	out := invoke(t, function, "", "", http.StatusOK)
+
+	var mounted map[string]secrets.Secret
+	if err := json.Unmarshal(out, &mounted); err != nil {
+		t.Fatalf("invalid secrets response %.200q: %s", out, err)
+	}
+
+	secret, ok := mounted[name]
+	if !ok {
+		t.Fatalf("secret %s is not mounted, got %v", name, mountedNames(mounted))
+	}
+	return secret
+}
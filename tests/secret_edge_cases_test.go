@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	secrets "github.com/openfaas/certifier/functions/secrets"
+	sdk "github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_SecretEdgeCases(t *testing.T) {
+	ctx := context.Background()
+	limits := config.Profile.Secrets
+
+	binary := make([]byte, 64*1024)
+	rand.New(rand.NewSource(64)).Read(binary)
+	// make sure the value is not valid UTF-8
+	binary[0], binary[1] = 0xff, 0xfe
+
+	cases := []types.Secret{
+		{Name: "secret-edge-binary", RawValue: binary},
+		{Name: "secret-edge-whitespace", Value: "  leading spaces\nline two\r\nline three\t \n\n"},
+		{Name: "secret-edge-single-newline", Value: "\n"},
+		{Name: longSecretName(limits.NameMaxLength)},
+	}
+	cases[3].Value = "name at the length limit"
+
+	if limits.MaxBytes > 0 {
+		atLimit := make([]byte, limits.MaxBytes)
+		rand.New(rand.NewSource(limits.MaxBytes)).Read(atLimit)
+		cases = append(cases, types.Secret{Name: "secret-edge-size-limit", RawValue: atLimit})
+	}
+
+	names := make([]string, 0, len(cases))
+	referenced := map[string]bool{}
+	for i := range cases {
+		cases[i].Namespace = config.DefaultNamespace
+		names = append(names, cases[i].Name)
+		referenced[cases[i].Name] = true
+
+		createStatus, out := config.Client.CreateSecret(ctx, cases[i])
+		switch createStatus {
+		case http.StatusCreated, http.StatusAccepted, http.StatusOK:
+		default:
+			t.Fatalf("creating secret %.40s got %d, wanted %d or %d: %s",
+				cases[i].Name, createStatus, http.StatusOK, http.StatusAccepted, out)
+		}
+
+		secret := cases[i]
+		defer func() {
+			if err := config.Client.RemoveSecret(ctx, secret); err != nil {
+				t.Logf("error removing secret %.40s: %s", secret.Name, err)
+			}
+		}()
+	}
+
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "secrets"),
+		FunctionName: "test-secret-edge-cases",
+		Network:      "func_functions",
+		Secrets:      names,
+		Namespace:    config.DefaultNamespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err := waitForFunctionStatus(time.Minute, functionRequest.FunctionName, functionRequest.Namespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", functionRequest.FunctionName, err)
+	}
+
+	var mounted map[string]secrets.Secret
+	out := invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+	if err := json.Unmarshal(out, &mounted); err != nil {
+		t.Fatalf("invalid secrets response %.200q: %s", out, err)
+	}
+
+	for _, secret := range cases {
+		secret := secret
+		t.Run(secretCaseName(secret), func(t *testing.T) {
+			want := wantSecret(secret)
+			got, ok := mounted[secret.Name]
+			if !ok {
+				t.Fatalf("secret is not mounted, found %v", mountedNames(mounted))
+			}
+			if got != want {
+				t.Fatalf("mounted %d bytes with SHA-512 %.16s..., created %d bytes with %.16s..., the value was altered",
+					got.Bytes, got.SHA512, want.Bytes, want.SHA512)
+			}
+		})
+	}
+
+	t.Run("only the referenced secrets are mounted", func(t *testing.T) {
+		for name := range mounted {
+			if !referenced[name] {
+				t.Errorf("secret %s is mounted but was not referenced", name)
+			}
+		}
+	})
+
+	t.Run("value above the size limit is rejected", func(t *testing.T) {
+		if limits.MaxBytes <= 0 {
+			skipf(t, "the profile declares no secret size limit")
+		}
+
+		secret := types.Secret{
+			Name:      "secret-edge-size-above-limit",
+			RawValue:  make([]byte, limits.MaxBytes+1),
+			Namespace: config.DefaultNamespace,
+		}
+
+		createStatus, out := config.Client.CreateSecret(ctx, secret)
+		if createStatus >= http.StatusOK && createStatus < http.StatusMultipleChoices {
+			_ = config.Client.RemoveSecret(ctx, secret)
+		}
+		if createStatus < http.StatusBadRequest || createStatus >= http.StatusInternalServerError {
+			t.Fatalf("got %d, wanted a 4xx status for %d bytes above the %d bytes limit: %s",
+				createStatus, limits.MaxBytes+1, limits.MaxBytes, out)
+		}
+	})
+}
+
+func Test_DeployWithMissingSecret(t *testing.T) {
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "secrets"),
+		FunctionName: "test-secret-missing",
+		Network:      "func_functions",
+		Secrets:      []string{"secret-edge-missing"},
+		Namespace:    config.DefaultNamespace,
+	}
+
+	deployStatus := tryDeploy(functionRequest)
+
+	switch config.Profile.Secrets.Missing {
+	case missingSecretReject:
+		if deployStatus < http.StatusBadRequest || deployStatus >= http.StatusInternalServerError {
+			if deployStatus == http.StatusOK || deployStatus == http.StatusAccepted {
+				deleteFunction(t, functionRequest)
+			}
+			t.Fatalf("got %d, wanted a 4xx status for a missing secret", deployStatus)
+		}
+	case missingSecretUnavailable:
+		if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+			t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+		}
+		defer deleteFunction(t, functionRequest)
+
+		if !statusNeverReached(t, 30*time.Second, functionRequest.FunctionName, functionRequest.Namespace, minAvailableReplicaCount(1)) {
+			t.Fatalf("function became available without its secret")
+		}
+	}
+}
+
+// longSecretName returns a valid secret name of exactly length characters
+// that uses every allowed character class: lower case letters, digits, '-'
+// and '.'.
+func longSecretName(length int) string {
+	name := "secret-edge.name-0"
+	if len(name) >= length {
+		return name[:length-1] + "0"
+	}
+	return name + strings.Repeat("a", length-len(name)-1) + "9"
+}
+
+func secretCaseName(secret types.Secret) string {
+	if len(secret.Name) > 40 {
+		return secret.Name[:40] + "..."
+	}
+	return secret.Name
+}
+
+func mountedNames(mounted map[string]secrets.Secret) []string {
+	names := make([]string, 0, len(mounted))
+	for name := range mounted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,255 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-provider/logs"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_SecretValuesDoNotLeak(t *testing.T) {
+	ctx := context.Background()
+	namespace := config.DefaultNamespace
+
+	scanner := &leakScanner{}
+	timeout := 30 * time.Second
+	client, err := sdk.NewClient(config.Auth, config.Gateway, scanner, &timeout)
+	if err != nil {
+		t.Fatalf("can not create client: %s", err)
+	}
+
+	stringSecret := types.Secret{
+		Name:      "secret-leak-string",
+		Value:     hex.EncodeToString(randomMarker(t)),
+		Namespace: namespace,
+	}
+	rawSecret := types.Secret{
+		Name:      "secret-leak-raw",
+		RawValue:  randomMarker(t),
+		Namespace: namespace,
+	}
+	scanner.Watch(stringSecret.Name, []byte(stringSecret.Value))
+	scanner.Watch(rawSecret.Name, rawSecret.RawValue)
+
+	for _, secret := range []types.Secret{stringSecret, rawSecret} {
+		secret := secret
+		createStatus, out := client.CreateSecret(ctx, secret)
+		switch createStatus {
+		case http.StatusCreated, http.StatusAccepted, http.StatusOK:
+		default:
+			t.Fatalf("creating secret %s got %d, wanted %d or %d: %s",
+				secret.Name, createStatus, http.StatusOK, http.StatusAccepted, out)
+		}
+		defer func() { _ = config.Client.RemoveSecret(ctx, secret) }()
+	}
+
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "secrets"),
+		FunctionName: "test-secret-leak",
+		Network:      "func_functions",
+		Secrets:      []string{stringSecret.Name, rawSecret.Name},
+		Namespace:    namespace,
+	}
+
+	deployStatus := client.DeployFunction(ctx, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err = waitForFunctionStatus(time.Minute, functionRequest.FunctionName, namespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", functionRequest.FunctionName, err)
+	}
+
+	// the fixture is the only place allowed to see the values, it is
+	// invoked without the scanner and reports only their hashes
+	for _, secret := range []types.Secret{stringSecret, rawSecret} {
+		if got, want := mountedSecret(t, functionRequest, secret.Name), wantSecret(secret); got != want {
+			t.Fatalf("secret %s is not mounted as created, got %+v, wanted %+v", secret.Name, got, want)
+		}
+	}
+
+	if _, err := client.GetSecretList(ctx, namespace); err != nil {
+		t.Errorf("list secrets failed: %s", err)
+	}
+	if _, err := client.ListFunctions(ctx, namespace); err != nil {
+		t.Errorf("list functions failed: %s", err)
+	}
+	if _, err := client.GetFunctionInfo(ctx, functionRequest.FunctionName, namespace); err != nil {
+		t.Errorf("describe failed: %s", err)
+	}
+	if _, err := client.GetSystemInfo(ctx); err != nil {
+		t.Errorf("system info failed: %s", err)
+	}
+
+	logsCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	messages, err := client.GetLogs(logsCtx, logs.Request{Name: functionRequest.FunctionName, Namespace: namespace, Tail: 100})
+	if err != nil {
+		t.Errorf("logs failed: %s", err)
+	} else {
+		for range messages {
+		}
+	}
+
+	// error responses, the values are sent again in requests that fail
+	_, _ = client.CreateSecret(ctx, stringSecret)
+	missing := stringSecret
+	missing.Name = "secret-leak-missing"
+	_, _ = client.UpdateSecret(ctx, missing)
+	invalid := rawSecret
+	invalid.Name = "Secret_Leak/Invalid"
+	if status, _ := client.CreateSecret(ctx, invalid); status < http.StatusBadRequest {
+		_ = client.RemoveSecret(ctx, invalid)
+	}
+	bad := *functionRequest
+	bad.FunctionName = "test-secret-leak-missing"
+	bad.Secrets = []string{missing.Name}
+	if status := client.DeployFunction(ctx, &bad); status < http.StatusBadRequest {
+		_ = client.DeleteFunction(ctx, bad.FunctionName, namespace)
+	}
+
+	t.Logf("scanned %d responses", scanner.Responses())
+	for _, leak := range scanner.Leaks() {
+		t.Error(leak)
+	}
+}
+
+// leakScanner is an http.RoundTripper that searches every response body
+// for the watched values, raw and in the encodings an API may use. With
+// watchRequests, the values of the secrets created or updated through it are
+// watched too.
+type leakScanner struct {
+	watchRequests bool
+
+	mu        sync.Mutex
+	markers   map[string][][]byte
+	responses int
+	leaks     []string
+}
+
+// Watch adds the value of the named secret to the scanned markers.
+func (s *leakScanner) Watch(name string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.markers == nil {
+		s.markers = map[string][][]byte{}
+	}
+	s.markers[name] = [][]byte{
+		value,
+		[]byte(base64.StdEncoding.EncodeToString(value)),
+		[]byte(base64.URLEncoding.EncodeToString(value)),
+		[]byte(hex.EncodeToString(value)),
+	}
+}
+
+func (s *leakScanner) RoundTrip(req *http.Request) (*http.Response, error) {
+	if s.watchRequests {
+		if err := s.watchSecretRequest(req); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.responses++
+	for name, encodings := range s.markers {
+		for _, marker := range encodings {
+			if bytes.Contains(body, marker) {
+				s.leaks = append(s.leaks, fmt.Sprintf("%s %s returned %d with the value of %s",
+					req.Method, req.URL.Path, res.StatusCode, name))
+				break
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// minWatchedBytes is the shortest value watched from the requests, shorter
+// values such as a single newline appear in responses by chance
+const minWatchedBytes = 16
+
+// watchSecretRequest watches the value of a secret sent to create or update
+// it, the body is restored for the request.
+func (s *leakScanner) watchSecretRequest(req *http.Request) error {
+	if req.Body == nil || !strings.HasSuffix(req.URL.Path, "/system/secrets") {
+		return nil
+	}
+	if req.Method != http.MethodPost && req.Method != http.MethodPut {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var secret types.Secret
+	if json.Unmarshal(body, &secret) != nil {
+		return nil
+	}
+	if len(secret.Value) >= minWatchedBytes {
+		s.Watch(secret.Name, []byte(secret.Value))
+	}
+	if len(secret.RawValue) >= minWatchedBytes {
+		s.Watch(secret.Name+" (raw)", secret.RawValue)
+	}
+	return nil
+}
+
+// Responses returns the number of scanned responses.
+func (s *leakScanner) Responses() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.responses
+}
+
+// Leaks describes every response that contained a watched value.
+func (s *leakScanner) Leaks() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.leaks...)
+}
+
+// randomMarker returns 32 random bytes, which can not appear in a response
+// by chance.
+func randomMarker(t *testing.T) []byte {
+	t.Helper()
+
+	marker := make([]byte, 32)
+	if _, err := rand.Read(marker); err != nil {
+		t.Fatal(err)
+	}
+	return marker
+}
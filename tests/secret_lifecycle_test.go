@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+
+	secrets "github.com/openfaas/certifier/functions/secrets"
+	sdk "github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// secretObservation is how long a running function is watched for changes
+// that the profile says must not happen
+const secretObservation = time.Minute
+
+func Test_SecretHotUpdate(t *testing.T) {
+	if !config.SecretUpdate {
+		skipf(t, "secret update not enabled")
+	}
+
+	ctx := context.Background()
+	secret := types.Secret{
+		Name:      "secret-lifecycle-update",
+		Value:     "this-is-the-secret-value",
+		Namespace: config.DefaultNamespace,
+	}
+	defer func() { _ = config.Client.RemoveSecret(ctx, secret) }()
+	functionRequest := deploySecretFunction(t, "test-secret-hot-update", secret)
+	defer deleteFunction(t, functionRequest)
+
+	updated := secret
+	updated.Value = "this-is-the-NEW-secret-value"
+	updateStatus, out := config.Client.UpdateSecret(ctx, updated)
+	if updateStatus != http.StatusOK && updateStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d: %s", updateStatus, http.StatusOK, http.StatusAccepted, out)
+	}
+	start := time.Now()
+
+	wait := secretObservation
+	if config.Profile.Secrets.HotUpdate {
+		wait = config.Profile.Secrets.HotUpdateWindow.Duration
+	}
+
+	var mounted secrets.Secret
+	for {
+		got, err := readSecrets(t, functionRequest)
+		if err != nil {
+			t.Fatalf("function failed after the secret update: %s", err)
+		}
+		mounted = got[secret.Name]
+		if mounted == wantSecret(updated) || time.Since(start) > wait {
+			break
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	propagated := mounted == wantSecret(updated)
+	if propagated {
+		report.Add(t.Name(), "propagation", time.Since(start).Round(time.Second).String())
+		t.Logf("the update reached the running replica after %s", time.Since(start).Round(time.Second))
+	}
+
+	switch {
+	case config.Profile.Secrets.HotUpdate && !propagated:
+		t.Fatalf("the update did not reach the running replica within %s", wait)
+	case !config.Profile.Secrets.HotUpdate && propagated:
+		t.Fatalf("the update reached the running replica without a redeploy, set secrets.hotUpdate in the profile")
+	case !propagated && mounted != wantSecret(secret):
+		t.Fatalf("mounted %d bytes with SHA-512 %.16s..., wanted the value before the update", mounted.Bytes, mounted.SHA512)
+	}
+}
+
+func Test_SecretDeleteInUse(t *testing.T) {
+	ctx := context.Background()
+	secret := types.Secret{
+		Name:      "secret-lifecycle-delete",
+		Value:     "this-is-the-secret-value",
+		Namespace: config.DefaultNamespace,
+	}
+	defer func() { _ = config.Client.RemoveSecret(ctx, secret) }()
+	functionRequest := deploySecretFunction(t, "test-secret-delete-in-use", secret)
+	defer deleteFunction(t, functionRequest)
+
+	removeErr := config.Client.RemoveSecret(ctx, secret)
+	t.Logf("removing the mounted secret: %v", removeErr)
+
+	list, err := config.Client.GetSecretList(ctx, secret.Namespace)
+	if err != nil {
+		t.Fatalf("error listing secrets in namespace: %s, error: %s", secret.Namespace, err)
+	}
+	listed := listContains(list, secret.Name)
+
+	// the observed behaviour is recorded, whatever the profile declares
+	if removeErr == nil && !listed {
+		report.Add(t.Name(), "removal", "removed")
+	} else {
+		report.Add(t.Name(), "removal", "refused")
+	}
+
+	switch config.Profile.Secrets.DeleteInUse {
+	case deleteInUseRefuse:
+		if removeErr == nil || !listed {
+			t.Fatalf("the mounted secret was removed, wanted the removal refused")
+		}
+		watchSecret(t, functionRequest, secret)
+
+	case deleteInUseAllow:
+		if removeErr != nil || listed {
+			t.Fatalf("the mounted secret was not removed: %v", removeErr)
+		}
+		watchSecret(t, functionRequest, secret)
+
+	case deleteInUseBreak:
+		if removeErr != nil || listed {
+			t.Fatalf("the mounted secret was not removed: %v", removeErr)
+		}
+
+		start := time.Now()
+		for time.Since(start) < secretObservation {
+			got, err := readSecrets(t, functionRequest)
+			if err != nil || got[secret.Name] != wantSecret(secret) {
+				lost := time.Since(start).Round(time.Second)
+				report.Add(t.Name(), "function", fmt.Sprintf("lost the secret after %s", lost))
+				t.Logf("the function lost the secret after %s: %v", lost, err)
+				return
+			}
+			time.Sleep(2 * time.Second)
+		}
+		report.Add(t.Name(), "function", "kept the secret")
+		t.Fatalf("the function still served the removed secret after %s", secretObservation)
+	}
+}
+
+// deploySecretFunction creates the secret and deploys the secrets fixture
+// with it mounted.
+func deploySecretFunction(t *testing.T, name string, secret types.Secret) *sdk.DeployFunctionSpec {
+	t.Helper()
+
+	createStatus, out := config.Client.CreateSecret(context.Background(), secret)
+	switch createStatus {
+	case http.StatusCreated, http.StatusAccepted, http.StatusOK:
+	default:
+		t.Fatalf("creating secret %s.%s got %d, wanted %d or %d: %s",
+			secret.Name, secret.Namespace, createStatus, http.StatusOK, http.StatusAccepted, out)
+	}
+
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "secrets"),
+		FunctionName: name,
+		Network:      "func_functions",
+		Secrets:      []string{secret.Name},
+		Namespace:    secret.Namespace,
+	}
+
+	deployStatus := deploy(t, functionRequest)
+	if deployStatus != http.StatusOK && deployStatus != http.StatusAccepted {
+		t.Fatalf("got %d, wanted %d or %d", deployStatus, http.StatusOK, http.StatusAccepted)
+	}
+
+	err := waitForFunctionStatus(time.Minute, name, secret.Namespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Fatalf("Function %q failed to start: %s", name, err)
+	}
+
+	var got map[string]secrets.Secret
+	body := invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid secrets response %.200q: %s", body, err)
+	}
+	if got[secret.Name] != wantSecret(secret) {
+		t.Fatalf("the secret is not mounted as created, found %v", got)
+	}
+
+	return functionRequest
+}
+
+// watchSecret fails the test when the function stops serving the secret
+// during secretObservation, the outcome is recorded in the report.
+func watchSecret(t *testing.T, function *sdk.DeployFunctionSpec, secret types.Secret) {
+	t.Helper()
+
+	start := time.Now()
+	for time.Since(start) < secretObservation {
+		got, err := readSecrets(t, function)
+		if err != nil || got[secret.Name] != wantSecret(secret) {
+			lost := time.Since(start).Round(time.Second)
+			report.Add(t.Name(), "function", fmt.Sprintf("lost the secret after %s", lost))
+			if err != nil {
+				t.Fatalf("the function failed after %s: %s", lost, err)
+			}
+			t.Fatalf("the function lost the secret after %s, found %v", lost, got)
+		}
+		time.Sleep(2 * time.Second)
+	}
+	report.Add(t.Name(), "function", "kept the secret")
+}
+
+// readSecrets invokes the secrets fixture once, without retries.
+func readSecrets(t *testing.T, function *sdk.DeployFunctionSpec) (map[string]secrets.Secret, error) {
+	t.Helper()
+
+	uri := resourceURL(t, path.Join("function", fmt.Sprintf("%s.%s", function.FunctionName, function.Namespace)), "")
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Post(uri, "text/plain", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got %d, wanted %d: %.200s", res.StatusCode, http.StatusOK, body)
+	}
+
+	var mounted map[string]secrets.Secret
+	if err := json.Unmarshal(body, &mounted); err != nil {
+		return nil, fmt.Errorf("invalid secrets response %.200q: %w", body, err)
+	}
+	return mounted, nil
+}
+
+// wantSecret is what the secrets fixture reports for the secret value.
+func wantSecret(secret types.Secret) secrets.Secret {
+	value := secret.RawValue
+	if len(value) == 0 {
+		value = []byte(secret.Value)
+	}
+
+	sum := sha512.Sum512(value)
+	return secrets.Secret{Bytes: int64(len(value)), SHA512: hex.EncodeToString(sum[:])}
+}
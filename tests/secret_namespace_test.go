@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	secrets "github.com/openfaas/certifier/functions/secrets"
+	sdk "github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_CrossNamespaceSecretReference(t *testing.T) {
+	if len(config.Namespaces) == 0 {
+		skipf(t, "set CERTIFIER_NAMESPACES to check the secret namespace isolation")
+	}
+
+	ctx := context.Background()
+	secret := types.Secret{
+		Name:      "secret-other-namespace",
+		Value:     hex.EncodeToString(randomMarker(t)),
+		Namespace: config.Namespaces[0],
+	}
+	createNamespacedSecret(t, secret)
+	defer func() { _ = config.Client.RemoveSecret(ctx, secret) }()
+
+	functionRequest := &sdk.DeployFunctionSpec{
+		Image:        fixtureImage(t, "secrets"),
+		FunctionName: "test-secret-cross-namespace",
+		Network:      "func_functions",
+		Secrets:      []string{secret.Name},
+		Namespace:    config.DefaultNamespace,
+	}
+
+	deployStatus := tryDeploy(functionRequest)
+	if deployStatus >= http.StatusBadRequest {
+		t.Logf("the deployment was rejected with %d", deployStatus)
+		return
+	}
+	defer deleteFunction(t, functionRequest)
+
+	err := waitForFunctionStatus(time.Minute, functionRequest.FunctionName, functionRequest.Namespace, minAvailableReplicaCount(1))
+	if err != nil {
+		t.Logf("the function did not become available: %s", err)
+		return
+	}
+
+	var mounted map[string]secrets.Secret
+	out := invoke(t, functionRequest, emptyQueryString, "", http.StatusOK)
+	if err := json.Unmarshal(out, &mounted); err != nil {
+		t.Fatalf("invalid secrets response %.200q: %s", out, err)
+	}
+
+	if _, ok := mounted[secret.Name]; ok {
+		t.Fatalf("a function in %s mounted the secret %s from %s", functionRequest.Namespace, secret.Name, secret.Namespace)
+	}
+}
+
+func Test_RemoveSecretWrongNamespace(t *testing.T) {
+	if len(config.Namespaces) == 0 {
+		skipf(t, "set CERTIFIER_NAMESPACES to check the secret namespace isolation")
+	}
+
+	cases := []struct {
+		name  string
+		owner string
+		wrong string
+	}{
+		{
+			name:  "from " + config.DefaultNamespace,
+			owner: config.Namespaces[0],
+			wrong: config.DefaultNamespace,
+		},
+		{
+			name:  "from " + config.Namespaces[0],
+			owner: config.DefaultNamespace,
+			wrong: config.Namespaces[0],
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			secret := types.Secret{
+				Name:      "secret-wrong-namespace",
+				Value:     "this-is-the-secret-value",
+				Namespace: tc.owner,
+			}
+			createNamespacedSecret(t, secret)
+			defer func() { _ = config.Client.RemoveSecret(ctx, secret) }()
+
+			wrong := secret
+			wrong.Namespace = tc.wrong
+			err := config.Client.RemoveSecret(ctx, wrong)
+			t.Logf("removing %s from %s: %v", secret.Name, tc.wrong, err)
+
+			list, err := config.Client.GetSecretList(ctx, tc.owner)
+			if err != nil {
+				t.Fatalf("error listing secrets in namespace: %s, error: %s", tc.owner, err)
+			}
+			if !listContains(list, secret.Name) {
+				t.Fatalf("removing %s from %s deleted the secret in %s", secret.Name, tc.wrong, tc.owner)
+			}
+		})
+	}
+}
+
+// createNamespacedSecret creates the secret after checking that the other
+// test namespaces do not have a secret with the same name, which would hide
+// a namespace mix up.
+func createNamespacedSecret(t *testing.T, secret types.Secret) {
+	t.Helper()
+
+	ctx := context.Background()
+	for _, namespace := range append([]string{config.DefaultNamespace}, config.Namespaces...) {
+		list, err := config.Client.GetSecretList(ctx, namespace)
+		if err != nil {
+			t.Fatalf("error listing secrets in namespace: %s, error: %s", namespace, err)
+		}
+		if listContains(list, secret.Name) {
+			t.Fatalf("namespace %s already has secret %s", namespace, secret.Name)
+		}
+	}
+
+	createStatus, out := config.Client.CreateSecret(ctx, secret)
+	switch createStatus {
+	case http.StatusCreated, http.StatusAccepted, http.StatusOK:
+	default:
+		t.Fatalf("creating secret %s.%s got %d, wanted %d or %d: %s",
+			secret.Name, secret.Namespace, createStatus, http.StatusOK, http.StatusAccepted, out)
+	}
+}
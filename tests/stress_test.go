@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+const stressPrefix = "test-stress-"
+
+func Test_ManagementAPIStress(t *testing.T) {
+	if config.Stress <= 0 {
+		skipf(t, "set -stress to the number of functions to stress the management API with")
+	}
+
+	recorder := &apiRecorder{}
+	timeout := 30 * time.Second
+	client, err := sdk.NewClient(config.Auth, config.Gateway, recorder, &timeout)
+	if err != nil {
+		t.Fatalf("can not create client: %s", err)
+	}
+
+	specs := make([]*sdk.DeployFunctionSpec, config.Stress)
+	for i := range specs {
+		specs[i] = &sdk.DeployFunctionSpec{
+			Image:        fixtureImage(t, "hash"),
+			FunctionName: fmt.Sprintf("%s%03d", stressPrefix, i),
+			Network:      "func_functions",
+			EnvVars:      map[string]string{"revision": "1"},
+			Namespace:    config.DefaultNamespace,
+		}
+	}
+
+	// the SDK prints every deployment, which is noise for hundreds of them
+	stdout := os.Stdout
+	os.Stdout = devnull
+	defer func() { os.Stdout = stdout }()
+
+	defer func() {
+		for _, spec := range specs {
+			_ = config.Client.DeleteFunction(context.Background(), spec.FunctionName, spec.Namespace)
+		}
+	}()
+
+	defer func() {
+		report.Add(t.Name(), "api", recorder.Summary())
+		t.Logf("API calls by endpoint:%s", recorder)
+	}()
+
+	ctx := context.Background()
+
+	stressPhase(t, "deploy", specs, func(spec *sdk.DeployFunctionSpec) error {
+		if status := client.DeployFunction(ctx, spec); status != http.StatusOK && status != http.StatusAccepted {
+			return fmt.Errorf("deploy %s got %d", spec.FunctionName, status)
+		}
+		return nil
+	})
+
+	stressPhase(t, "update", specs, func(spec *sdk.DeployFunctionSpec) error {
+		update := *spec
+		update.Update = true
+		update.EnvVars = map[string]string{"revision": "2"}
+		if status := client.DeployFunction(ctx, &update); status != http.StatusOK && status != http.StatusAccepted {
+			return fmt.Errorf("update %s got %d", spec.FunctionName, status)
+		}
+		return nil
+	})
+
+	// scaling to zero also frees the cluster for the hundreds of functions
+	if config.EnableScaling {
+		stressPhase(t, "scale", specs, func(spec *sdk.DeployFunctionSpec) error {
+			return client.ScaleFunction(ctx, spec.FunctionName, spec.Namespace, 0)
+		})
+	}
+
+	stressConverge(t, client, func(functions []types.FunctionStatus) []string {
+		var problems []string
+		seen := map[string]int{}
+		for _, fn := range functions {
+			if !strings.HasPrefix(fn.Name, stressPrefix) {
+				continue
+			}
+			seen[fn.Name]++
+			if fn.EnvVars["revision"] != "2" {
+				problems = append(problems, fmt.Sprintf("%s has revision %q, the update was lost", fn.Name, fn.EnvVars["revision"]))
+			}
+			if config.EnableScaling && fn.Replicas != 0 {
+				problems = append(problems, fmt.Sprintf("%s has %d replicas, the scale to 0 was lost", fn.Name, fn.Replicas))
+			}
+		}
+
+		for _, spec := range specs {
+			switch seen[spec.FunctionName] {
+			case 0:
+				problems = append(problems, spec.FunctionName+" is missing")
+			case 1:
+			default:
+				problems = append(problems, fmt.Sprintf("%s is listed %d times", spec.FunctionName, seen[spec.FunctionName]))
+			}
+			delete(seen, spec.FunctionName)
+		}
+
+		for name := range seen {
+			problems = append(problems, name+" was never deployed")
+		}
+		return problems
+	})
+
+	stressPhase(t, "delete", specs, func(spec *sdk.DeployFunctionSpec) error {
+		return client.DeleteFunction(ctx, spec.FunctionName, spec.Namespace)
+	})
+
+	stressConverge(t, client, func(functions []types.FunctionStatus) []string {
+		var problems []string
+		for _, fn := range functions {
+			if strings.HasPrefix(fn.Name, stressPrefix) {
+				problems = append(problems, fn.Name+" is still listed after delete")
+			}
+		}
+		return problems
+	})
+
+	if n := recorder.ServerErrors(); n > 0 {
+		t.Fatalf("the management API returned %d 5xx responses", n)
+	}
+}
+
+// stressPhase calls fn for every spec with -stressConcurrency calls in
+// flight and fails the test when any call failed.
+func stressPhase(t testing.TB, name string, specs []*sdk.DeployFunctionSpec, fn func(*sdk.DeployFunctionSpec) error) {
+	t.Helper()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []string
+		queued = make(chan *sdk.DeployFunctionSpec)
+	)
+
+	start := time.Now()
+	for i := 0; i < config.StressConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for spec := range queued {
+				if err := fn(spec); err != nil {
+					mu.Lock()
+					errs = append(errs, err.Error())
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, spec := range specs {
+		queued <- spec
+	}
+	close(queued)
+	wg.Wait()
+
+	t.Logf("%s of %d functions took %s", name, len(specs), time.Since(start).Round(time.Millisecond))
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		t.Fatalf("%d of %d calls failed during %s:\n%s", len(errs), len(specs), name, strings.Join(firstLines(errs, 10), "\n"))
+	}
+}
+
+// stressConverge polls ListFunctions until check reports no problems.
+func stressConverge(t testing.TB, client *sdk.Client, check func([]types.FunctionStatus) []string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ScaleTimeout)
+	defer cancel()
+
+	var problems []string
+	for ctx.Err() == nil {
+		functions, err := client.ListFunctions(ctx, config.DefaultNamespace)
+		if err != nil {
+			problems = []string{err.Error()}
+		} else if problems = check(functions); len(problems) == 0 {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+
+	sort.Strings(problems)
+	t.Fatalf("ListFunctions did not converge within %s, %d problems:\n%s",
+		config.ScaleTimeout, len(problems), strings.Join(firstLines(problems, 10), "\n"))
+}
+
+func firstLines(lines []string, n int) []string {
+	if len(lines) <= n {
+		return lines
+	}
+	return append(lines[:n:n], fmt.Sprintf("... %d more", len(lines)-n))
+}
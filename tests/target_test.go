@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"testing"
+
+	sdk "github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-provider/types"
+)
+
+// The API the checks are pointed at with -target. The provider serves the
+// faas-provider HTTP surface that the gateway proxies, so the same checks
+// can certify a FaaSHandlers implementation on its own.
+const (
+	targetGateway  = "gateway"
+	targetProvider = "provider"
+)
+
+// requireGateway skips checks of features that the gateway implements on
+// top of the provider, e.g. autoscaling and scale from zero.
+func requireGateway(t testing.TB, feature string) {
+	t.Helper()
+
+	if config.Target != targetGateway {
+		skipf(t, "%s is implemented by the gateway, skipped with -target=%s", feature, config.Target)
+	}
+}
+
+// systemInfo returns the provider info and, with the gateway target, the
+// gateway version from /system/info. The provider serves its own info
+// document, without the gateway version.
+func systemInfo(ctx context.Context, client *sdk.Client) (types.ProviderInfo, *types.VersionInfo, error) {
+	if config.Target == targetGateway {
+		info, err := client.GetSystemInfo(ctx)
+		if err != nil {
+			return types.ProviderInfo{}, nil, err
+		}
+		if info.Provider == nil {
+			return types.ProviderInfo{}, info.Version, fmt.Errorf("provider info should be present")
+		}
+		return *info.Provider, info.Version, nil
+	}
+
+	uri, err := url.Parse(config.Gateway)
+	if err != nil {
+		return types.ProviderInfo{}, nil, err
+	}
+	uri.Path = path.Join(uri.Path, "system", "info")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return types.ProviderInfo{}, nil, err
+	}
+	if err := config.Auth.Set(req); err != nil {
+		return types.ProviderInfo{}, nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return types.ProviderInfo{}, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return types.ProviderInfo{}, nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return types.ProviderInfo{}, nil, fmt.Errorf("/system/info got %d: %s", res.StatusCode, body)
+	}
+
+	var info types.ProviderInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return info, nil, fmt.Errorf("invalid provider info %q: %w", body, err)
+	}
+	return info, nil, nil
+}